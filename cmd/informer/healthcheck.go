@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/langchou/informer/pkg/config"
+)
+
+// runHealthcheck 请求本进程的 /healthz，返回进程退出码：0 正常，1 异常
+func runHealthcheck(cfg *config.Config) int {
+	if !cfg.Health.Enabled {
+		fmt.Println("未启用健康检查接口，跳过检查")
+		return 0
+	}
+
+	host, port, err := net.SplitHostPort(cfg.Admin.Listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "监听地址无效: %v\n", err)
+		return 1
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s/healthz", net.JoinHostPort(host, port)))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "健康检查请求失败: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	fmt.Print(string(body))
+	if resp.StatusCode != http.StatusOK {
+		return 1
+	}
+	return 0
+}
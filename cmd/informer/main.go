@@ -2,16 +2,24 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
-	"github.com/langchou/informer/db"
-	"github.com/langchou/informer/internal/monitor"
+	mydb "github.com/langchou/informer/db"
+	"github.com/langchou/informer/internal/admin"
+	mymonitor "github.com/langchou/informer/internal/monitor"
+	"github.com/langchou/informer/pkg/checker"
 	"github.com/langchou/informer/pkg/config"
+	"github.com/langchou/informer/pkg/cookie"
+	"github.com/langchou/informer/pkg/fetch"
 	mylog "github.com/langchou/informer/pkg/log"
 	"github.com/langchou/informer/pkg/notifier"
 	"github.com/langchou/informer/pkg/proxy"
-	"golang.org/x/exp/rand"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -24,6 +32,11 @@ func main() {
 		log.Fatalf("初始化配置失败: %v", err)
 	}
 
+	// informer healthcheck：供 Docker HEALTHCHECK 调用
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		os.Exit(runHealthcheck(cfg))
+	}
+
 	mylog.InitLogger(
 		cfg.LogConfig.File,
 		cfg.LogConfig.MaxSize,
@@ -35,54 +48,182 @@ func main() {
 
 	defer mylog.Sync()
 
-	db, err := db.InitDB(dbFile)
+	db, err := mydb.InitDB(dbFile)
 	if err != nil {
 		mylog.Error("无法初始化数据库", "error", err)
 		return
 	}
 	defer db.DB.Close()
 
-	// 创建chiphell表
-	err = db.CreateTableIfNotExists("chiphell")
+	// 初始化 DingTalk 客户端
+	dingNotifier := notifier.NewDingTalkNotifier(cfg.DingTalk.Token, cfg.DingTalk.Secret)
+
+	// 所有请求共享的 cookie jar，保存服务器下发的 cookies 并定期写入 cookieFile
+	jar, err := cookie.NewJar(cfg.CookieFile)
 	if err != nil {
-		mylog.Error("无法创建数据表", "error", err)
+		mylog.Error(fmt.Sprintf("初始化 cookie jar 失败: %v", err))
 		return
 	}
 
-	// 初始化 DingTalk 客户端
-	dingNotifier := notifier.NewDingTalkNotifier(cfg.DingTalk.Token, cfg.DingTalk.Secret)
+	// 所有子系统共用的 HTTP 客户端，按代理复用连接
+	client := fetch.NewClient(
+		fetch.WithTimeout(time.Duration(cfg.HTTP.TimeoutSeconds)*time.Second),
+		fetch.WithHeaders(map[string]string{"User-Agent": cfg.HTTP.UserAgent}),
+		fetch.WithCookieJar(jar),
+		fetch.WithInsecureProxyTLS(cfg.HTTP.InsecureProxyTLS),
+	)
+	directClient, _ := client.HTTPClient("")
+
+	// 代理检测使用不带 cookie jar 的客户端：检测请求不携带登录状态，
+	// 检测时收到的 Set-Cookie（如验证码页面）也不会写入真实会话
+	checkClient := fetch.NewClient(
+		fetch.WithTimeout(time.Duration(cfg.HTTP.TimeoutSeconds)*time.Second),
+		fetch.WithHeaders(map[string]string{"User-Agent": cfg.HTTP.UserAgent}),
+		fetch.WithInsecureProxyTLS(cfg.HTTP.InsecureProxyTLS),
+	)
+
+	// 代理池，由抓取、代理检测和管理接口共用
+	pool := proxy.NewPool(
+		proxy.WithAPI(cfg.ProxyPoolAPI),
+		proxy.WithSources(proxySources(cfg, directClient)...),
+		proxy.WithTTL(time.Duration(cfg.ProxyPool.TTLMinutes)*time.Minute),
+		proxy.WithMinRatio(cfg.ProxyPool.MinRatio),
+		proxy.WithHTTPClient(directClient),
+		proxy.WithRemoveHook(client.Forget),
+		proxy.WithRemoveHook(checkClient.Forget),
+	)
+
+	// 收到 SIGINT/SIGTERM 时取消 ctx，各后台任务随之退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// 设置 ProxyAPI
-	proxy.SetProxyAPI(cfg.ProxyPoolAPI)
+	// 先从上次保存的状态恢复代理池和评分，再从代理来源更新
+	if pool.Enabled() {
+		if err := pool.LoadState(cfg.ProxyPool.StateFile); err != nil {
+			mylog.Error(fmt.Sprintf("恢复代理池失败: %v", err))
+		}
 
-	// 初始化时更新一次代理池
-	if err := proxy.UpdateProxyPool(); err != nil {
-		mylog.Error("初始化代理池失败", "error", err)
+		if err := pool.Update(ctx); err != nil {
+			mylog.Error("初始化代理池失败", "error", err)
+		}
 	}
 
-	// 启动代理池管理器
-	ctx := context.Background()
-	go proxy.StartProxyPoolManager(ctx)
+	var wg sync.WaitGroup
+	goWithWait := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	// 启动代理池管理器，并定期及退出时保存代理池
+	goWithWait(func() { pool.StartManager(ctx) })
+	if pool.Enabled() {
+		goWithWait(func() { pool.StartAutoSave(ctx, cfg.ProxyPool.StateFile, time.Minute) })
+	}
 
-	// 启动IP检测器
-	go proxy.StartIPChecker(ctx)
+	// 启动帖子记录清理任务
+	if cfg.Retention.Enabled {
+		goWithWait(func() {
+			db.StartCleanupJob(ctx, "chiphell", mydb.RetentionPolicy{
+				KeepDuration: time.Duration(cfg.Retention.KeepDays) * 24 * time.Hour,
+				KeepMatched:  cfg.Retention.KeepMatched,
+				Interval:     time.Duration(cfg.Retention.IntervalHours) * time.Hour,
+			})
+		})
+	}
+
+	// 定期保存 cookie jar
+	goWithWait(func() { jar.StartAutoSave(ctx, time.Minute) })
 
 	// 创建并启动监控器
-	monitor := monitor.NewMonitor(
-		cfg.Cookies,
+	monitor := mymonitor.NewMonitor(
+		jar,
 		cfg.UserKeyWords,
 		dingNotifier,
 		db,
 		cfg.WaitTimeRange,
-		cfg.ProxyPoolAPI,
+		pool,
 	)
+	monitor.Client = client
+	monitor.UserAliases = cfg.UserAliases
+	monitor.Strategy = mymonitor.FetchStrategy(cfg.HTTP.Strategy)
+	monitor.DirectCooldown = time.Duration(cfg.HTTP.DirectCooldownMinutes) * time.Minute
+	monitor.RaceProxies = cfg.HTTP.RaceProxies
+	monitor.CookieAlertInterval = time.Duration(cfg.CookieAlertIntervalMinutes) * time.Minute
+	monitor.Account = mymonitor.Account{
+		Username:   cfg.Account.Username,
+		Password:   cfg.Account.Password,
+		QuestionID: cfg.Account.QuestionID,
+		Answer:     cfg.Account.Answer,
+	}
+
+	// 导入配置中的 cookies（配置未变化时沿用 cookieFile 中保存的 cookies），没有 cookies 时尝试登录
+	monitor.SeedCookies(cfg.Cookies)
+	if !monitor.HasCookies() && monitor.Account.Configured() {
+		if err := monitor.Login(ctx); err != nil {
+			mylog.Error(fmt.Sprintf("自动登录失败: %v", err))
+		}
+	}
+	monitor.PriceTracking = mymonitor.PriceTracking{
+		Enabled:        cfg.PriceHistory.Enabled,
+		Window:         time.Duration(cfg.PriceHistory.WindowDays) * 24 * time.Hour,
+		MinSamples:     cfg.PriceHistory.MinSamples,
+		DealPercentile: cfg.PriceHistory.DealPercentile,
+	}
+	monitor.RepostDetection = mymonitor.RepostDetection{
+		Enabled:  cfg.RepostDetection.Enabled,
+		Window:   time.Duration(cfg.RepostDetection.WindowHours) * time.Hour,
+		Suppress: cfg.RepostDetection.Action == "suppress",
+	}
+	monitor.ThreadTracking = mymonitor.ThreadTracking{
+		Enabled:     cfg.ThreadTracking.Enabled,
+		Duration:    time.Duration(cfg.ThreadTracking.Days) * 24 * time.Hour,
+		Interval:    time.Duration(cfg.ThreadTracking.IntervalMinutes) * time.Minute,
+		SoldMarkers: cfg.ThreadTracking.SoldMarkers,
+	}
 
-	// 主循环
-	for {
-		monitor.MonitorPage()
-		// 添加随机等待时间
-		waitTime := time.Duration(cfg.WaitTimeRange.Min+rand.Intn(cfg.WaitTimeRange.Max-cfg.WaitTimeRange.Min)) * time.Second
-		mylog.Debug("等待 %v 后继续监控", waitTime)
-		time.Sleep(waitTime)
+	// 消息队列使用独立的 ctx，等所有产生通知的任务退出后再停止，保证待发送的通知全部发出
+	queueCtx, stopQueue := context.WithCancel(context.Background())
+	queueDone := make(chan struct{})
+	go func() {
+		defer close(queueDone)
+		monitor.ProcessMessageQueue(queueCtx)
+	}()
+
+	// 启动IP检测器，启动时立即检测一次
+	target := monitor.ProxyCheckTarget()
+	if cfg.ProxyCheck.URL != "" {
+		target = checker.Target{
+			URL:       cfg.ProxyCheck.URL,
+			Selector:  cfg.ProxyCheck.Selector,
+			Forbidden: cfg.ProxyCheck.Forbidden,
+		}
 	}
+	proxyChecker := checker.New(pool, checkClient, target,
+		checker.WithInterval(time.Duration(cfg.ProxyCheck.IntervalMinutes)*time.Minute),
+		checker.WithConcurrency(cfg.ProxyCheck.Concurrency),
+	)
+	goWithWait(func() { proxyChecker.Start(ctx) })
+
+	// 启动命中帖子回访
+	if monitor.ThreadTracking.Enabled {
+		goWithWait(func() { monitor.StartThreadTracker(ctx) })
+	}
+
+	// 启动管理接口与健康检查接口
+	if cfg.Admin.Enabled || cfg.Health.Enabled {
+		goWithWait(func() { admin.NewServer(cfg, monitor, db, proxyChecker).Start(ctx) })
+	}
+
+	// 主循环，收到退出信号后返回
+	monitor.MonitorPage(ctx)
+
+	mylog.Info("收到退出信号，等待后台任务结束")
+	wg.Wait()
+
+	stopQueue()
+	<-queueDone
+	mylog.Info("已发送剩余通知，程序退出")
 }
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/langchou/informer/pkg/config"
+	"github.com/langchou/informer/pkg/proxy"
+)
+
+// proxySources 根据配置创建代理来源，接口类来源使用 client 发送请求
+func proxySources(cfg *config.Config, client *http.Client) []proxy.ProxySource {
+	var sources []proxy.ProxySource
+	for _, source := range cfg.ProxySources {
+		switch source.Type {
+		case "static":
+			sources = append(sources, &proxy.StaticSource{Proxies: source.Proxies})
+		case "file":
+			sources = append(sources, &proxy.FileSource{Path: source.Path})
+		case "api":
+			sources = append(sources, &proxy.APISource{
+				URL:      source.URL,
+				Format:   source.Format,
+				Protocol: source.Protocol,
+				Client:   client,
+				Fields: proxy.JSONFields{
+					List:     source.JSON.List,
+					Address:  source.JSON.Address,
+					IP:       source.JSON.IP,
+					Port:     source.JSON.Port,
+					Protocol: source.JSON.Protocol,
+					Username: source.JSON.Username,
+					Password: source.JSON.Password,
+				},
+			})
+		}
+	}
+	return sources
+}
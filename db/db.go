@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	mylog "github.com/langchou/informer/pkg/log"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -13,28 +14,28 @@ type Database struct {
 	DB *sql.DB
 }
 
+// PostRecord 帖子记录
+type PostRecord struct {
+	PostID    string    `json:"postId"`
+	Title     string    `json:"title"`
+	SellerUID string    `json:"sellerUid,omitempty"`
+	Matched   bool      `json:"matched"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// InitDB 打开数据库并执行尚未应用的迁移，所有表结构均由 migrations 目录下的迁移文件维护
 func InitDB(filepath string) (*Database, error) {
 	db, err := sql.Open("sqlite3", filepath)
 	if err != nil {
 		return nil, err
 	}
-	return &Database{DB: db}, nil
-}
 
-func (d *Database) CreateTableIfNotExists(forum string) error {
-	tableName := fmt.Sprintf("%s_posts", forum)
-	createTableQuery := fmt.Sprintf(`
-	CREATE TABLE IF NOT EXISTS %s (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		post_id TEXT NOT NULL UNIQUE,
-		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
-	);`, tableName)
-
-	_, err := d.DB.Exec(createTableQuery)
-	if err != nil {
-		return fmt.Errorf("无法创建表 %s: %v", tableName, err)
+	database := &Database{DB: db}
+	if err := database.Migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("数据库迁移失败: %v", err)
 	}
-	return nil
+	return database, nil
 }
 
 func (d *Database) IsNewPost(forum, postID string) bool {
@@ -49,20 +50,106 @@ func (d *Database) IsNewPost(forum, postID string) bool {
 	return !exists
 }
 
-func (d *Database) StorePostID(forum, postID string) {
+func (d *Database) StorePostID(forum, postID, title string) {
 	tableName := fmt.Sprintf("%s_posts", forum)
-	insertQuery := fmt.Sprintf(`INSERT INTO %s (post_id) VALUES (?)`, tableName)
-	_, err := d.DB.Exec(insertQuery, postID)
+	insertQuery := fmt.Sprintf(`INSERT INTO %s (post_id, title, last_seen) VALUES (?, ?, CURRENT_TIMESTAMP)`, tableName)
+	_, err := d.DB.Exec(insertQuery, postID, title)
 	if err != nil {
 		mylog.Error("无法存储帖子ID", "error", err)
 	}
 }
 
-func (d *Database) CleanUpOldPosts(forum string, duration time.Duration) {
+// TouchPosts 刷新帖子最近一次出现在列表页的时间。列表页按最后回复排序，
+// 旧帖被顶起后仍会出现，保留策略按该时间清理，避免旧帖记录被删除后再次被当作新帖通知
+func (d *Database) TouchPosts(forum string, postIDs []string) {
+	if len(postIDs) == 0 {
+		return
+	}
+	tableName := fmt.Sprintf("%s_posts", forum)
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(postIDs)), ",")
+	updateQuery := fmt.Sprintf(`UPDATE %s SET last_seen = CURRENT_TIMESTAMP WHERE post_id IN (%s)`, tableName, placeholders)
+
+	args := make([]interface{}, len(postIDs))
+	for i, postID := range postIDs {
+		args[i] = postID
+	}
+	if _, err := d.DB.Exec(updateQuery, args...); err != nil {
+		mylog.Error(fmt.Sprintf("无法更新帖子最近出现时间: %v", err))
+	}
+}
+
+// MarkPostMatched 标记帖子命中过关键词，保留策略可据此永久保留该记录
+func (d *Database) MarkPostMatched(forum, postID string) {
+	tableName := fmt.Sprintf("%s_posts", forum)
+	updateQuery := fmt.Sprintf(`UPDATE %s SET matched = 1 WHERE post_id = ?`, tableName)
+	_, err := d.DB.Exec(updateQuery, postID)
+	if err != nil {
+		mylog.Error(fmt.Sprintf("无法标记帖子命中状态: %v", err))
+	}
+}
+
+// SetPostFingerprint 记录帖子的卖家 UID 与内容指纹，用于识别重新发布的帖子
+func (d *Database) SetPostFingerprint(forum, postID, sellerUID, fingerprint string) {
 	tableName := fmt.Sprintf("%s_posts", forum)
-	deleteQuery := fmt.Sprintf(`DELETE FROM %s WHERE timestamp < datetime('now', ?)`, tableName)
-	_, err := d.DB.Exec(deleteQuery, fmt.Sprintf("-%d seconds", int(duration.Seconds())))
+	updateQuery := fmt.Sprintf(`UPDATE %s SET seller_uid = ?, fingerprint = ? WHERE post_id = ?`, tableName)
+	_, err := d.DB.Exec(updateQuery, sellerUID, fingerprint, postID)
+	if err != nil {
+		mylog.Error(fmt.Sprintf("无法存储帖子指纹: %v", err))
+	}
+}
+
+// FindRepost 查找 window 时间内与指纹相同的其他帖子，返回最早的帖子ID，未找到时返回空字符串
+func (d *Database) FindRepost(forum, postID, fingerprint string, window time.Duration) (string, error) {
+	tableName := fmt.Sprintf("%s_posts", forum)
+	query := fmt.Sprintf(`SELECT post_id FROM %s WHERE fingerprint = ? AND post_id != ? AND timestamp >= datetime('now', ?) ORDER BY timestamp LIMIT 1`, tableName)
+
+	var originalID string
+	err := d.DB.QueryRow(query, fingerprint, postID, fmt.Sprintf("-%d seconds", int(window.Seconds()))).Scan(&originalID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询重复帖子失败: %v", err)
+	}
+	return originalID, nil
+}
+
+// RecentPosts 按时间倒序返回最近的帖子记录，onlyMatched 为 true 时只返回命中关键词的帖子
+func (d *Database) RecentPosts(forum string, limit int, onlyMatched bool) ([]PostRecord, error) {
+	tableName := fmt.Sprintf("%s_posts", forum)
+	query := fmt.Sprintf(`SELECT post_id, title, seller_uid, matched, timestamp FROM %s`, tableName)
+	if onlyMatched {
+		query += ` WHERE matched = 1`
+	}
+	query += ` ORDER BY timestamp DESC, id DESC LIMIT ?`
+
+	rows, err := d.DB.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询帖子记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	var posts []PostRecord
+	for rows.Next() {
+		var p PostRecord
+		if err := rows.Scan(&p.PostID, &p.Title, &p.SellerUID, &p.Matched, &p.Timestamp); err != nil {
+			return nil, fmt.Errorf("读取帖子记录失败: %v", err)
+		}
+		posts = append(posts, p)
+	}
+	return posts, rows.Err()
+}
+
+// CleanUpOldPosts 删除超过 duration 未在列表页出现的帖子记录，keepMatched 为 true 时保留命中过关键词的记录，返回删除的行数
+func (d *Database) CleanUpOldPosts(forum string, duration time.Duration, keepMatched bool) (int64, error) {
+	tableName := fmt.Sprintf("%s_posts", forum)
+	deleteQuery := fmt.Sprintf(`DELETE FROM %s WHERE last_seen < datetime('now', ?)`, tableName)
+	if keepMatched {
+		deleteQuery += ` AND matched = 0`
+	}
+	result, err := d.DB.Exec(deleteQuery, fmt.Sprintf("-%d seconds", int(duration.Seconds())))
 	if err != nil {
-		mylog.Error("无法清理旧帖子记录", "error", err)
+		return 0, fmt.Errorf("无法清理旧帖子记录: %v", err)
 	}
+	return result.RowsAffected()
 }
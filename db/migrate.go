@@ -0,0 +1,130 @@
+package db
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+// 迁移文件命名格式为 <版本号>_<描述>.sql，例如 0002_add_title.sql，
+// 按版本号顺序执行，每个版本只执行一次
+//
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// loadMigrations 读取内嵌的迁移文件并按版本号排序
+func loadMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移文件失败: %v", err)
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "migrations/")
+		prefix, _, ok := strings.Cut(name, "_")
+		if !ok {
+			return nil, fmt.Errorf("迁移文件名格式错误: %s", name)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("迁移文件版本号无效: %s", name)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("迁移版本号重复: %s 与 %s", other, name)
+		}
+		seen[version] = name
+
+		content, err := migrationFS.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件 %s 失败: %v", name, err)
+		}
+		migrations = append(migrations, migration{version: version, name: name, sql: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// Migrate 执行所有尚未应用的迁移，已应用的版本记录在 schema_migrations 表中
+func (d *Database) Migrate() error {
+	_, err := d.DB.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`)
+	if err != nil {
+		return fmt.Errorf("无法创建迁移记录表: %v", err)
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[int]bool)
+	rows, err := d.DB.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return fmt.Errorf("查询迁移记录失败: %v", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("读取迁移记录失败: %v", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("读取迁移记录失败: %v", err)
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := d.applyMigration(m); err != nil {
+			return err
+		}
+		mylog.Info(fmt.Sprintf("已应用数据库迁移: %s", m.name))
+	}
+	return nil
+}
+
+// applyMigration 在单个事务中执行迁移并记录版本，失败时整体回滚
+func (d *Database) applyMigration(m migration) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("开启迁移事务失败: %v", err)
+	}
+
+	if _, err := tx.Exec(m.sql); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("执行迁移 %s 失败: %v", m.name, err)
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("记录迁移 %s 失败: %v", m.name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交迁移 %s 失败: %v", m.name, err)
+	}
+	return nil
+}
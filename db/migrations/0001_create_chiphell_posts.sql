@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS chiphell_posts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id TEXT NOT NULL UNIQUE,
+	timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
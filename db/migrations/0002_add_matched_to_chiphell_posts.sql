@@ -0,0 +1 @@
+ALTER TABLE chiphell_posts ADD COLUMN matched INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS price_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	product_key TEXT NOT NULL,
+	forum TEXT NOT NULL,
+	post_id TEXT NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	price REAL NOT NULL,
+	timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (product_key, forum, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_history_key_time ON price_history (product_key, timestamp);
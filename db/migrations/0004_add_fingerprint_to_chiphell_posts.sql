@@ -0,0 +1,4 @@
+ALTER TABLE chiphell_posts ADD COLUMN seller_uid TEXT NOT NULL DEFAULT '';
+ALTER TABLE chiphell_posts ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_chiphell_posts_fingerprint ON chiphell_posts (fingerprint, timestamp);
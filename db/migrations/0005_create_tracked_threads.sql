@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tracked_threads (
+	forum TEXT NOT NULL,
+	post_id TEXT NOT NULL,
+	link TEXT NOT NULL,
+	title TEXT NOT NULL DEFAULT '',
+	price TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'active',
+	first_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
+	last_checked DATETIME,
+	PRIMARY KEY (forum, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tracked_threads_status ON tracked_threads (status, first_seen);
+
+ALTER TABLE price_history ADD COLUMN sold INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE chiphell_posts ADD COLUMN title TEXT NOT NULL DEFAULT '';
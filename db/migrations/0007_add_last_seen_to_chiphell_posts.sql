@@ -0,0 +1,4 @@
+ALTER TABLE chiphell_posts ADD COLUMN last_seen DATETIME;
+UPDATE chiphell_posts SET last_seen = timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_chiphell_posts_last_seen ON chiphell_posts (last_seen);
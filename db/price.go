@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecordPrice 记录某个商品关键词下帖子的价格，同一帖子只记录一次
+func (d *Database) RecordPrice(forum, productKey, postID, title string, price float64) error {
+	_, err := d.DB.Exec(`INSERT OR IGNORE INTO price_history (product_key, forum, post_id, title, price) VALUES (?, ?, ?, ?, ?)`,
+		productKey, forum, postID, title, price)
+	if err != nil {
+		return fmt.Errorf("无法记录价格: %v", err)
+	}
+	return nil
+}
+
+// RecentPrices 返回某个商品关键词在 window 时间内的历史价格，不包含 excludePostID 对应的帖子
+func (d *Database) RecentPrices(productKey string, window time.Duration, excludePostID string) ([]float64, error) {
+	rows, err := d.DB.Query(`SELECT price FROM price_history WHERE product_key = ? AND post_id != ? AND timestamp >= datetime('now', ?)`,
+		productKey, excludePostID, fmt.Sprintf("-%d seconds", int(window.Seconds())))
+	if err != nil {
+		return nil, fmt.Errorf("查询历史价格失败: %v", err)
+	}
+	defer rows.Close()
+
+	var prices []float64
+	for rows.Next() {
+		var price float64
+		if err := rows.Scan(&price); err != nil {
+			return nil, fmt.Errorf("读取历史价格失败: %v", err)
+		}
+		prices = append(prices, price)
+	}
+	return prices, rows.Err()
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+// RetentionPolicy 帖子记录的保留策略
+type RetentionPolicy struct {
+	KeepDuration time.Duration // 记录保留时长
+	KeepMatched  bool          // 是否永久保留命中过关键词的记录
+	Interval     time.Duration // 清理间隔
+}
+
+// StartCleanupJob 启动定期清理任务，启动时先执行一次
+func (d *Database) StartCleanupJob(ctx context.Context, forum string, policy RetentionPolicy) {
+	d.runCleanup(forum, policy)
+
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			d.runCleanup(forum, policy)
+		}
+	}
+}
+
+func (d *Database) runCleanup(forum string, policy RetentionPolicy) {
+	removed, err := d.CleanUpOldPosts(forum, policy.KeepDuration, policy.KeepMatched)
+	if err != nil {
+		mylog.Error(fmt.Sprintf("清理 %s 帖子记录失败: %v", forum, err))
+		return
+	}
+	mylog.Info(fmt.Sprintf("清理 %s 帖子记录完成，删除 %d 条超过 %v 的记录", forum, removed, policy.KeepDuration))
+
+	if removed > 0 {
+		if err := d.vacuum(); err != nil {
+			mylog.Warn(fmt.Sprintf("回收数据库空间失败: %v", err))
+		}
+	}
+}
+
+// vacuum 回收已删除记录占用的空间，auto_vacuum 为 INCREMENTAL 时使用增量回收
+func (d *Database) vacuum() error {
+	var mode int
+	if err := d.DB.QueryRow(`PRAGMA auto_vacuum`).Scan(&mode); err != nil {
+		return err
+	}
+
+	if mode == 2 {
+		_, err := d.DB.Exec(`PRAGMA incremental_vacuum`)
+		return err
+	}
+	_, err := d.DB.Exec(`VACUUM`)
+	return err
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// 跟踪帖子的状态
+const (
+	ThreadActive  = "active"
+	ThreadSold    = "sold"
+	ThreadDeleted = "deleted"
+	ThreadExpired = "expired"
+)
+
+// TrackedThread 需要定期回访的帖子
+type TrackedThread struct {
+	PostID    string
+	Link      string
+	Title     string
+	Price     string
+	Status    string
+	FirstSeen time.Time
+}
+
+// TrackThread 开始跟踪帖子，已跟踪的帖子不会重复添加
+func (d *Database) TrackThread(forum, postID, link, title, price string) error {
+	_, err := d.DB.Exec(`INSERT OR IGNORE INTO tracked_threads (forum, post_id, link, title, price) VALUES (?, ?, ?, ?, ?)`,
+		forum, postID, link, title, price)
+	if err != nil {
+		return fmt.Errorf("无法跟踪帖子: %v", err)
+	}
+	return nil
+}
+
+// ActiveTrackedThreads 返回仍在跟踪中的帖子，并将超过 maxAge 的帖子标记为过期
+func (d *Database) ActiveTrackedThreads(forum string, maxAge time.Duration) ([]TrackedThread, error) {
+	age := fmt.Sprintf("-%d seconds", int(maxAge.Seconds()))
+	_, err := d.DB.Exec(`UPDATE tracked_threads SET status = ? WHERE forum = ? AND status = ? AND first_seen < datetime('now', ?)`,
+		ThreadExpired, forum, ThreadActive, age)
+	if err != nil {
+		return nil, fmt.Errorf("无法更新过期跟踪帖子: %v", err)
+	}
+
+	rows, err := d.DB.Query(`SELECT post_id, link, title, price, status, first_seen FROM tracked_threads WHERE forum = ? AND status = ? ORDER BY first_seen`,
+		forum, ThreadActive)
+	if err != nil {
+		return nil, fmt.Errorf("查询跟踪帖子失败: %v", err)
+	}
+	defer rows.Close()
+
+	var threads []TrackedThread
+	for rows.Next() {
+		var t TrackedThread
+		if err := rows.Scan(&t.PostID, &t.Link, &t.Title, &t.Price, &t.Status, &t.FirstSeen); err != nil {
+			return nil, fmt.Errorf("读取跟踪帖子失败: %v", err)
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+// UpdateTrackedThread 更新回访得到的标题、价格和状态，帖子已售出时同步标记价格历史
+func (d *Database) UpdateTrackedThread(forum, postID, title, price, status string) error {
+	_, err := d.DB.Exec(`UPDATE tracked_threads SET title = ?, price = ?, status = ?, last_checked = CURRENT_TIMESTAMP WHERE forum = ? AND post_id = ?`,
+		title, price, status, forum, postID)
+	if err != nil {
+		return fmt.Errorf("无法更新跟踪帖子: %v", err)
+	}
+
+	if status == ThreadSold {
+		_, err = d.DB.Exec(`UPDATE price_history SET sold = 1 WHERE forum = ? AND post_id = ?`, forum, postID)
+		if err != nil {
+			return fmt.Errorf("无法标记价格历史为已售: %v", err)
+		}
+	}
+	return nil
+}
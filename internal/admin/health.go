@@ -0,0 +1,25 @@
+package admin
+
+import (
+	"net/http"
+)
+
+// handleHealthz 存活检查：超过 staleAfter 未成功解析到帖子时返回 503，暂停期间视为正常
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	health := s.monitor.Health(s.staleAfter)
+	if !health.Alive {
+		writeJSON(w, http.StatusServiceUnavailable, health)
+		return
+	}
+	writeJSON(w, http.StatusOK, health)
+}
+
+// handleReadyz 就绪检查：尚未成功解析过帖子、数据已过期或监控暂停时返回 503
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	health := s.monitor.Health(s.staleAfter)
+	if !health.Ready {
+		writeJSON(w, http.StatusServiceUnavailable, health)
+		return
+	}
+	writeJSON(w, http.StatusOK, health)
+}
@@ -0,0 +1,248 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/langchou/informer/db"
+	"github.com/langchou/informer/internal/monitor"
+	"github.com/langchou/informer/pkg/checker"
+	"github.com/langchou/informer/pkg/config"
+	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/proxy"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const redacted = "******"
+
+// Server 管理接口，用于查看运行状态和控制监控，同时提供健康检查接口
+type Server struct {
+	addr       string
+	token      string
+	staleAfter time.Duration
+	cfg        *config.Config
+	monitor    *monitor.ChiphellMonitor
+	database   *db.Database
+	checker    *checker.Checker
+	mux        *http.ServeMux
+}
+
+// NewServer 根据配置注册接口：admin.enabled 时注册管理接口和指标，health.enabled 时注册健康检查接口
+func NewServer(cfg *config.Config, m *monitor.ChiphellMonitor, database *db.Database, proxyChecker *checker.Checker) *Server {
+	s := &Server{
+		addr:       cfg.Admin.Listen,
+		token:      cfg.Admin.Token,
+		staleAfter: time.Duration(cfg.Health.StaleMinutes) * time.Minute,
+		cfg:        cfg,
+		monitor:    m,
+		database:   database,
+		checker:    proxyChecker,
+		mux:        http.NewServeMux(),
+	}
+
+	if cfg.Health.Enabled {
+		s.mux.HandleFunc("GET /healthz", s.handleHealthz)
+		s.mux.HandleFunc("GET /readyz", s.handleReadyz)
+	}
+
+	if !cfg.Admin.Enabled {
+		return s
+	}
+	// 管理接口可以暂停监控并查看配置，未配置 token 时只允许监听本机地址
+	if s.token == "" && !isLoopback(s.addr) {
+		mylog.Warn(fmt.Sprintf("管理接口监听于 %s 但未配置 admin.token，已禁用管理接口（仅本机地址允许不配置 token）", s.addr))
+		return s
+	}
+	s.mux.HandleFunc("GET /api/config", s.auth(s.handleConfig))
+	s.mux.HandleFunc("GET /api/status", s.auth(s.handleStatus))
+	s.mux.HandleFunc("GET /api/proxies", s.auth(s.handleProxies))
+	s.mux.HandleFunc("GET /api/proxies/check", s.auth(s.handleProxyCheck))
+	s.mux.HandleFunc("GET /api/posts", s.auth(s.handlePosts(false)))
+	s.mux.HandleFunc("GET /api/matches", s.auth(s.handlePosts(true)))
+	s.mux.HandleFunc("GET /api/outbox", s.auth(s.handleOutbox))
+	s.mux.HandleFunc("POST /api/pause", s.auth(s.handlePause))
+	s.mux.HandleFunc("POST /api/resume", s.auth(s.handleResume))
+	s.mux.HandleFunc("POST /api/poll", s.auth(s.handlePoll))
+	s.mux.Handle("GET /metrics", s.auth(promhttp.Handler().ServeHTTP))
+
+	return s
+}
+
+// Start 启动 HTTP 服务，ctx 取消时关闭
+func (s *Server) Start(ctx context.Context) {
+	server := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	mylog.Info(fmt.Sprintf("管理接口监听于 %s", s.addr))
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		mylog.Error(fmt.Sprintf("管理接口启动失败: %v", err))
+	}
+}
+
+// isLoopback 监听地址是否只在本机可访问，未指定主机时监听所有网卡
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// auth 配置了 token 时要求请求携带 Authorization: Bearer <token>
+func (s *Server) auth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, redactConfig(*s.cfg))
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	pool := s.monitor.Pool
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"paused":         s.monitor.Paused(),
+		"fetch":          s.monitor.FetchStatus(),
+		"outbox":         s.monitor.OutboxStatus(),
+		"proxyCount":     pool.Count(),
+		"preferredCount": pool.PreferredCount(),
+		"proxyCheck":     s.checker.Progress(),
+	})
+}
+
+func (s *Server) handleProxies(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.monitor.Pool.Snapshot())
+}
+
+func (s *Server) handleProxyCheck(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.checker.Progress())
+}
+
+func (s *Server) handlePosts(onlyMatched bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := 50
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 || n > 1000 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit 取值范围为 1-1000"})
+				return
+			}
+			limit = n
+		}
+
+		posts, err := s.database.RecentPosts(s.monitor.ForumName, limit, onlyMatched)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		if posts == nil {
+			posts = []db.PostRecord{}
+		}
+		writeJSON(w, http.StatusOK, posts)
+	}
+}
+
+func (s *Server) handleOutbox(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.monitor.OutboxStatus())
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.monitor.Pause()
+	mylog.Info("通过管理接口暂停监控")
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": true})
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.monitor.Resume()
+	mylog.Info("通过管理接口恢复监控")
+	writeJSON(w, http.StatusOK, map[string]bool{"paused": false})
+}
+
+func (s *Server) handlePoll(w http.ResponseWriter, r *http.Request) {
+	s.monitor.TriggerPoll()
+	mylog.Info("通过管理接口触发立即监控")
+	writeJSON(w, http.StatusAccepted, map[string]bool{"triggered": true})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		mylog.Debug(fmt.Sprintf("写入管理接口响应失败: %v", err))
+	}
+}
+
+// redactConfig 隐藏配置中的密钥、cookies 等敏感信息
+func redactConfig(cfg config.Config) config.Config {
+	cfg.DingTalk.Token = redactSecret(cfg.DingTalk.Token)
+	cfg.DingTalk.Secret = redactSecret(cfg.DingTalk.Secret)
+	cfg.Cookies = redactSecret(cfg.Cookies)
+	cfg.Account.Password = redactSecret(cfg.Account.Password)
+	cfg.Account.Answer = redactSecret(cfg.Account.Answer)
+	cfg.Admin.Token = redactSecret(cfg.Admin.Token)
+	cfg.ProxyPoolAPI = redactURL(cfg.ProxyPoolAPI)
+	sources := make([]config.ProxySource, len(cfg.ProxySources))
+	for i, source := range cfg.ProxySources {
+		source.URL = redactURL(source.URL)
+		source.Proxies = make([]string, len(cfg.ProxySources[i].Proxies))
+		for j, address := range cfg.ProxySources[i].Proxies {
+			source.Proxies[j] = proxy.Redact(address)
+		}
+		sources[i] = source
+	}
+	cfg.ProxySources = sources
+	return cfg
+}
+
+func redactSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL 隐藏 URL 中的用户信息和查询参数（通常包含 API key）
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return redacted
+	}
+	if u.User != nil {
+		u.User = url.User("redacted")
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = "redacted"
+	}
+	return u.String()
+}
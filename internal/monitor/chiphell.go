@@ -1,29 +1,36 @@
 package monitor
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/langchou/informer/db"
+	"github.com/langchou/informer/pkg/checker"
+	"github.com/langchou/informer/pkg/cookie"
 	"github.com/langchou/informer/pkg/fetch"
 	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/metrics"
 	"github.com/langchou/informer/pkg/notifier"
 	"github.com/langchou/informer/pkg/proxy"
 	"golang.org/x/exp/rand"
 )
 
 type Post struct {
-	Title string
-	Link  string
+	Title     string
+	Link      string
+	SellerUID string
 }
 
 type ChiphellMonitor struct {
 	ForumName     string
-	Cookies       string
+	Jar           *cookie.Jar
 	UserKeywords  map[string][]string
+	UserAliases   map[string]string // 手机号对应的别名，用作指标中的 user 标签
 	Notifier      *notifier.DingTalkNotifier
 	Database      *db.Database
 	MessageQueue  chan NotificationMessage
@@ -31,38 +38,65 @@ type ChiphellMonitor struct {
 		Min int `yaml:"min"`
 		Max int `yaml:"max"`
 	}
-	ProxyAPI string
+	// Pool 抓取使用的代理池，未配置代理来源时直连
+	Pool *proxy.Pool
+	// Client 发送请求的客户端
+	Client *fetch.Client
+	// Strategy 直连与代理的使用方式，为空时按是否配置代理来源决定
+	Strategy FetchStrategy
+	// DirectCooldown 直连返回 403/429 后改用代理的时长
+	DirectCooldown time.Duration
+	// RaceProxies 大于 1 时列表页同时通过该数量的代理请求，采用最先返回的有效页面
+	RaceProxies     int
+	PriceTracking   PriceTracking
+	RepostDetection RepostDetection
+	ThreadTracking  ThreadTracking
+	// CookieAlertInterval 两次 Cookie 失效告警之间的最小间隔
+	CookieAlertInterval time.Duration
+	// Account 配置后在检测到未登录时自动登录
+	Account Account
+
+	state *runtimeState
 }
 
+// PostDetail 主楼中的交易信息
+type PostDetail struct {
+	Title      string
+	QQ         string
+	Price      string
+	TradeRange string
+	Address    string
+	Phone      string
+}
+
+// ErrThreadDeleted 帖子不存在、已被删除或正在审核
+var ErrThreadDeleted = errors.New("帖子不存在或已被删除")
+
 type NotificationMessage struct {
 	Title         string
 	Message       string
 	AtPhoneNumber []string
 }
 
-func NewMonitor(cookies string, userKeywords map[string][]string, notifier *notifier.DingTalkNotifier, database *db.Database, waitTimeRange struct{ Min int `yaml:"min"`; Max int `yaml:"max"` }, proxyAPI string) *ChiphellMonitor {
+func NewMonitor(jar *cookie.Jar, userKeywords map[string][]string, notifier *notifier.DingTalkNotifier, database *db.Database, waitTimeRange struct{ Min int `yaml:"min"`; Max int `yaml:"max"` }, pool *proxy.Pool) *ChiphellMonitor {
 	monitor := &ChiphellMonitor{
 		ForumName:     "chiphell",
-		Cookies:       cookies,
+		Jar:           jar,
 		UserKeywords:  userKeywords,
 		Notifier:      notifier,
 		Database:      database,
 		MessageQueue:  make(chan NotificationMessage, 100),
 		WaitTimeRange: waitTimeRange,
-		ProxyAPI:      proxyAPI,
+		Pool:          pool,
+		Client:        fetch.Default(),
+		state:         newRuntimeState(),
 	}
 
-	// 设置 ProxyAPI
-	proxy.SetProxyAPI(proxyAPI)
-
-	// 启动 goroutine 处理消息队列
-	go monitor.processMessageQueue()
-
 	return monitor
 }
 
-// 修改消息队列的处理函数，批量处理消息
-func (c *ChiphellMonitor) processMessageQueue() {
+// ProcessMessageQueue 批量处理消息队列，ctx 取消时发送队列中剩余的消息后返回
+func (c *ChiphellMonitor) ProcessMessageQueue(ctx context.Context) {
 	ticker := time.NewTicker(3 * time.Second)
 	defer ticker.Stop()
 
@@ -71,104 +105,126 @@ func (c *ChiphellMonitor) processMessageQueue() {
 		select {
 		case msg := <-c.MessageQueue:
 			messages = append(messages, msg)
+			c.recordOutbox(len(messages), 0, nil)
 		case <-ticker.C:
 			if len(messages) > 0 {
-				// 批量发送所有积累的消息
-				var combinedMessage strings.Builder
-				var allPhoneNumbers []string
-				phoneNumbersMap := make(map[string]bool)
-
-				for i, msg := range messages {
-					// 添加分隔线（除了第一条消息）
-					if i > 0 {
-						combinedMessage.WriteString("\n----------------------------------------\n\n")
-					}
-					
-					// 处理消息内容
-					lines := strings.Split(msg.Message, "\n")
-					for _, line := range lines {
-						if strings.Contains(line, "链接:") {
-							parts := strings.SplitN(line, ":", 2)
-							if len(parts) == 2 {
-								url := strings.TrimSpace(parts[1])
-								combinedMessage.WriteString(fmt.Sprintf("【链接】%s\n\n", url))
-							}
-							continue
-						}
+				c.sendBatch(messages)
+				// 清空消息列表
+				messages = nil
+			}
+		case <-ctx.Done():
+			// 取出队列中剩余的消息一并发送
+		drain:
+			for {
+				select {
+				case msg := <-c.MessageQueue:
+					messages = append(messages, msg)
+				default:
+					break drain
+				}
+			}
+			if len(messages) > 0 {
+				mylog.Info(fmt.Sprintf("退出前发送剩余的 %d 条通知", len(messages)))
+				c.sendBatch(messages)
+			}
+			return
+		}
+	}
+}
 
-						// 跳过系统信息部分
-						if strings.Contains(line, "系统信息:") || 
-						   strings.Contains(line, "当前时间:") || 
-						   strings.Contains(line, "可用代理数:") || 
-						   strings.Contains(line, "优选代理数:") {
-							continue
-						}
+// sendBatch 将多条消息合并为一条钉钉文本消息发送
+func (c *ChiphellMonitor) sendBatch(messages []NotificationMessage) {
+	// 批量发送所有积累的消息
+	var combinedMessage strings.Builder
+	var allPhoneNumbers []string
+	phoneNumbersMap := make(map[string]bool)
+
+	for i, msg := range messages {
+		// 添加分隔线（除了第一条消息）
+		if i > 0 {
+			combinedMessage.WriteString("\n----------------------------------------\n\n")
+		}
+		
+		// 处理消息内容
+		lines := strings.Split(msg.Message, "\n")
+		for _, line := range lines {
+			if strings.Contains(line, "链接:") {
+				parts := strings.SplitN(line, ":", 2)
+				if len(parts) == 2 {
+					url := strings.TrimSpace(parts[1])
+					combinedMessage.WriteString(fmt.Sprintf("【链接】%s\n\n", url))
+				}
+				continue
+			}
 
-						// 处理其他信息
-						if strings.Contains(line, ":") {
-							parts := strings.SplitN(line, ":", 2)
-							if len(parts) == 2 {
-								key := strings.TrimSpace(parts[0])
-								value := strings.TrimSpace(parts[1])
-								if value != "" && value != "-" {
-									if strings.Contains(key, "价格") {
-										combinedMessage.WriteString(fmt.Sprintf("【价格】%s\n\n", value))
-									} else if strings.Contains(key, "电话") || strings.Contains(key, "QQ") {
-										combinedMessage.WriteString(fmt.Sprintf("【%s】%s\n\n", key, value))
-									} else if strings.Contains(key, "所在地") {
-										combinedMessage.WriteString(fmt.Sprintf("【所在地】%s\n\n", value))
-									} else if strings.Contains(key, "交易范围") {
-										combinedMessage.WriteString(fmt.Sprintf("【交易范围】%s\n\n", value))
-									} else if strings.Contains(key, "当前时间") {
-										// 跳过当前时间信息
-										continue
-									} else if strings.Contains(key, "代理数") {
-										// 跳过代理数信息
-										continue
-									} else if strings.Contains(key, "标题") {
-										combinedMessage.WriteString(fmt.Sprintf("【新帖】%s\n\n", value))
-									} else {
-										combinedMessage.WriteString(fmt.Sprintf("【%s】%s\n\n", key, value))
-									}
-								}
-							}
-						}
-					}
+			// 跳过系统信息部分
+			if strings.Contains(line, "系统信息:") || 
+			   strings.Contains(line, "当前时间:") || 
+			   strings.Contains(line, "可用代理数:") || 
+			   strings.Contains(line, "优选代理数:") {
+				continue
+			}
 
-					// 收集所有需要@的手机号，去重
-					for _, phone := range msg.AtPhoneNumber {
-						if !phoneNumbersMap[phone] {
-							phoneNumbersMap[phone] = true
-							allPhoneNumbers = append(allPhoneNumbers, phone)
+			// 处理其他信息
+			if strings.Contains(line, ":") {
+				parts := strings.SplitN(line, ":", 2)
+				if len(parts) == 2 {
+					key := strings.TrimSpace(parts[0])
+					value := strings.TrimSpace(parts[1])
+					if value != "" && value != "-" {
+						if strings.Contains(key, "价格") {
+							combinedMessage.WriteString(fmt.Sprintf("【价格】%s\n\n", value))
+						} else if strings.Contains(key, "电话") || strings.Contains(key, "QQ") {
+							combinedMessage.WriteString(fmt.Sprintf("【%s】%s\n\n", key, value))
+						} else if strings.Contains(key, "所在地") {
+							combinedMessage.WriteString(fmt.Sprintf("【所在地】%s\n\n", value))
+						} else if strings.Contains(key, "交易范围") {
+							combinedMessage.WriteString(fmt.Sprintf("【交易范围】%s\n\n", value))
+						} else if strings.Contains(key, "当前时间") {
+							// 跳过当前时间信息
+							continue
+						} else if strings.Contains(key, "代理数") {
+							// 跳过代理数信息
+							continue
+						} else if strings.Contains(key, "标题") {
+							combinedMessage.WriteString(fmt.Sprintf("【新帖】%s\n\n", value))
+						} else {
+							combinedMessage.WriteString(fmt.Sprintf("【%s】%s\n\n", key, value))
 						}
 					}
 				}
+			}
+		}
 
-				// 打印消息内容摘要
-				contentPreview := combinedMessage.String()
-				if len(contentPreview) > 100 {
-					contentPreview = contentPreview[:100] + "..."
-				}
-				mylog.Debug(fmt.Sprintf("消息内容预览: %s", contentPreview))
-				
-				// 只发送一条text消息
-				err := c.Notifier.SendTextNotification(
-					"新帖子通知",
-					combinedMessage.String(),
-					allPhoneNumbers,
-				)
-
-				if err != nil {
-					mylog.Error(fmt.Sprintf("发送钉钉通知失败: %v", err))
-				} else {
-					mylog.Debug(fmt.Sprintf("成功发送%d条合并消息", len(messages)))
-				}
-
-				// 清空消息列表
-				messages = nil
+		// 收集所有需要@的手机号，去重
+		for _, phone := range msg.AtPhoneNumber {
+			if !phoneNumbersMap[phone] {
+				phoneNumbersMap[phone] = true
+				allPhoneNumbers = append(allPhoneNumbers, phone)
 			}
 		}
 	}
+
+	// 打印消息内容摘要
+	contentPreview := combinedMessage.String()
+	if len(contentPreview) > 100 {
+		contentPreview = contentPreview[:100] + "..."
+	}
+	mylog.Debug(fmt.Sprintf("消息内容预览: %s", contentPreview))
+	
+	// 只发送一条text消息
+	err := c.Notifier.SendTextNotification(
+		"新帖子通知",
+		combinedMessage.String(),
+		allPhoneNumbers,
+	)
+
+	if err != nil {
+		mylog.Error(fmt.Sprintf("发送钉钉通知失败: %v", err))
+	} else {
+		mylog.Debug(fmt.Sprintf("成功发送%d条合并消息", len(messages)))
+	}
+	c.recordOutbox(0, len(messages), err)
 }
 
 // 将通知消息放入队列
@@ -182,96 +238,21 @@ func (c *ChiphellMonitor) enqueueNotification(title, message string, atPhoneNumb
 	c.MessageQueue <- notification
 }
 
-// 获取页面内容
-// FetchPageContent 使用代理池并发请求访问论坛页面
-func (c *ChiphellMonitor) FetchPageContent() (string, error) {
-	if c.ProxyAPI != "" {
-		headers := map[string]string{
-			"Cookie":     c.Cookies,
-			"User-Agent": "Mozilla/5.0",
-		}
-
-		content, err := fetch.FetchWithProxies("https://www.chiphell.com/forum-26-1.html", headers)
-		if err != nil {
-			return "", err
-		}
-		return content, nil
-	} else {
-		return c.fetchWithoutProxy()
-	}
-}
-
-func (c *ChiphellMonitor) fetchWithProxy(proxyIP string) (string, error) {
-	proxyURL, err := fetch.ParseProxyURL(proxyIP)
-	if err != nil {
-		return "", fmt.Errorf("解析代理 URL 失败: %v", err)
-	}
-
-	client := &http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyURL(proxyURL),
-		},
-	}
-
-	req, err := http.NewRequest("GET", "https://www.chiphell.com/forum-26-1.html", nil)
-	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	req.Header.Set("Cookie", c.Cookies)
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-	req.Header.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("无效的响应状态码: %d", resp.StatusCode)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("解析 HTML 失败: %v", err)
-	}
-
-	// 记录使用的代理 IP
-	mylog.Debug(fmt.Sprintf("成功使用代理 IP: %s", proxyIP))
-
-	html, _ := doc.Html()
-	return html, nil
+// FetchPageContent 按抓取策略访问论坛列表页。开启并发竞速时访问受限和验证码页面不视为有效页面，
+// 未登录的页面照常返回，由 ParseContent 判断
+func (c *ChiphellMonitor) FetchPageContent(ctx context.Context) (string, error) {
+	return c.fetchPage(ctx, listURL, checkListing)
 }
 
-func (c *ChiphellMonitor) fetchWithoutProxy() (string, error) {
-	client := &http.Client{}
-
-	req, err := http.NewRequest("GET", "https://www.chiphell.com/forum-26-1.html", nil)
-	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	req.Header.Set("Cookie", c.Cookies)
-	req.Header.Set("User-Agent", "Mozilla/5.0")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("无效的响应状态码: %d", resp.StatusCode)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("解析 HTML 失败: %v", err)
+// ProxyCheckTarget 代理检测目标：能打开二手区列表页且未被限制访问的代理才作为优选代理。
+// 检测请求不携带 cookies，未登录时论坛可能显示登录表单而不是帖子列表，两者都说明代理可以正常访问论坛。
+// 与抓取时相同，只有页面没有帖子且带有访问受限特征时才视为被限制，CDN 注入到正常页面中的脚本不影响判断
+func (c *ChiphellMonitor) ProxyCheckTarget() checker.Target {
+	return checker.Target{
+		URL:      listURL,
+		Selector: "#threadlisttableid, #lsform, form[name='login']",
+		Validate: checkListing,
 	}
-
-	html, _ := doc.Html()
-	return html, nil
 }
 
 func (c *ChiphellMonitor) ParseContent(content string) ([]Post, error) {
@@ -286,36 +267,53 @@ func (c *ChiphellMonitor) ParseContent(content string) ([]Post, error) {
 		postLink := s.Find("a.s.xst")
 		postTitle := postLink.Text()
 
+		// 第一个 td.by 为发帖人，第二个为最后回复人
+		authorHref, _ := s.Find("td.by").First().Find("cite a").Attr("href")
+
 		postHref, exists := postLink.Attr("href")
 		if exists {
 			posts = append(posts, Post{
-				Title: postTitle,
-				Link:  "https://www.chiphell.com/" + postHref,
+				Title:     postTitle,
+				Link:      "https://www.chiphell.com/" + postHref,
+				SellerUID: extractUID(authorHref),
 			})
 		}
 	})
-	return posts, nil
-}
 
-func (c *ChiphellMonitor) FetchPostMainContent(postURL string) (string, string, string, string, string, error) {
-	headers := map[string]string{
-		"Cookie":     c.Cookies,
-		"User-Agent": "Mozilla/5.0",
+	// 访问受限、验证码页面同样没有帖子，先排除这类页面，避免误判为 Cookie 失效
+	if len(posts) == 0 {
+		if blocked, reason := detectBlocked(doc, content); blocked {
+			return nil, fmt.Errorf("%w: %s", fetch.ErrBlocked, reason)
+		}
 	}
+	// 未登录但游客仍能看到帖子时，同时返回帖子和 ErrLoggedOut，由调用方照常处理帖子并告警
+	if loggedOut, reason := detectLoggedOut(doc, content, len(posts)); loggedOut {
+		return posts, fmt.Errorf("%w: %s", ErrLoggedOut, reason)
+	}
+	return posts, nil
+}
 
-	// 使用代理池获取主楼内容
-	content, err := fetch.FetchWithProxies(postURL, headers)
+func (c *ChiphellMonitor) FetchPostMainContent(ctx context.Context, postURL string) (*PostDetail, error) {
+	// 按抓取策略获取主楼内容
+	content, err := c.fetchPage(ctx, postURL, nil)
 	if err != nil {
-		return "", "", "", "", "", fmt.Errorf("获取主楼内容失败: %v", err)
+		return nil, fmt.Errorf("获取主楼内容失败: %v", err)
 	}
 
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
 	if err != nil {
-		return "", "", "", "", "", fmt.Errorf("解析 HTML 失败: %v", err)
+		return nil, fmt.Errorf("解析 HTML 失败: %v", err)
+	}
+
+	// 帖子被删除或审核中时论坛返回提示页而不是帖子内容
+	if doc.Find("#thread_subject").Length() == 0 && strings.Contains(doc.Find("#messagetext, .alert_error").Text(), "不存在") {
+		return nil, ErrThreadDeleted
 	}
 
 	// 提取信息
-	var qq, price, tradeRange, address, phone string
+	detail := &PostDetail{
+		Title: strings.TrimSpace(doc.Find("#thread_subject").Text()),
+	}
 
 	// 调整选择器以直接定位表格中的行
 	doc.Find(".typeoption tbody tr").Each(func(i int, tr *goquery.Selection) {
@@ -324,50 +322,98 @@ func (c *ChiphellMonitor) FetchPostMainContent(postURL string) (string, string,
 
 		switch th {
 		case "所在地:":
-			address = td
+			detail.Address = td
 		case "电话:":
-			phone = td
+			detail.Phone = td
 		case "QQ:":
-			qq = td
+			detail.QQ = td
 		case "价格:":
-			price = td
+			detail.Price = td
 		case "交易范围:":
-			tradeRange = td
+			detail.TradeRange = td
 		}
 	})
 
-	return qq, price, tradeRange, address, phone, nil
+	return detail, nil
 }
 
-func (c *ChiphellMonitor) ProcessPosts(posts []Post) error {
+func (c *ChiphellMonitor) ProcessPosts(ctx context.Context, posts []Post) error {
+	// 已记录过的帖子，处理完后统一刷新其最近出现时间
+	var seen []string
 	for _, post := range posts {
 		// 从帖子链接中提取ID
 		postID := extractPostID(post.Link)
 
 		if c.Database.IsNewPost(c.ForumName, postID) {
-			c.Database.StorePostID(c.ForumName, postID)
+			c.Database.StorePostID(c.ForumName, postID, post.Title)
+			metrics.NewPosts.Inc()
 			mylog.Info(fmt.Sprintf("检测到新帖子: 标题: %s 链接: %s", post.Title, post.Link))
 
 			// 构建基本消息
 			basicMessage := fmt.Sprintf("标题: %s\n\n链接: %s", post.Title, post.Link)
 
 			// 尝试获取主楼内容
-			qq, price, tradeRange, address, phone, err := c.FetchPostMainContent(post.Link)
+			detail, err := c.FetchPostMainContent(ctx, post.Link)
 			if err != nil {
 				mylog.Error(fmt.Sprintf("获取主楼内容失败: %v", err))
+				detail = nil
+			}
+
+			// 检查是否为同一卖家重新发布的帖子
+			var priceText string
+			if detail != nil {
+				priceText = detail.Price
+			}
+			if originalID := c.checkRepost(post, postID, priceText); originalID != "" {
+				if c.RepostDetection.Suppress {
+					mylog.Info(fmt.Sprintf("跳过重新发布的帖子通知: %s", post.Title))
+					continue
+				}
+				basicMessage += fmt.Sprintf("\n\n状态: 重新发布（原帖 %s）", originalID)
+			}
+
+			if detail == nil {
 				// 即使获取详情失败，也发送基本信息
-				c.processNotification(post.Title, basicMessage)
+				c.processNotification(postID, post.Title, basicMessage, nil)
 			} else {
 				// 构建完整消息，每个字段之间添加空行
-				detailMessage := fmt.Sprintf("标题: %s\n\n链接: %s\n\nQQ: %s\n\n电话: %s\n\n价格: %s\n\n所在地: %s\n\n交易范围: %s",
-					post.Title, post.Link, qq, phone, price, address, tradeRange)
-				c.processNotification(post.Title, detailMessage)
+				detailMessage := fmt.Sprintf("%s\n\nQQ: %s\n\n电话: %s\n\n价格: %s\n\n所在地: %s\n\n交易范围: %s",
+					basicMessage, detail.QQ, detail.Phone, detail.Price, detail.Address, detail.TradeRange)
+				c.processNotification(postID, post.Title, detailMessage, detail)
 			}
+		} else {
+			seen = append(seen, postID)
 		}
 	}
+
+	c.Database.TouchPosts(c.ForumName, seen)
 	return nil
 }
 
+// randomWaitTime 返回 WaitTimeRange 范围内的随机等待时间
+func (c *ChiphellMonitor) randomWaitTime() time.Duration {
+	return time.Duration(c.WaitTimeRange.Min+rand.Intn(c.WaitTimeRange.Max-c.WaitTimeRange.Min+1)) * time.Second
+}
+
+// fetchFailureReason 将抓取错误归类，用于监控指标
+func fetchFailureReason(err error) string {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, fetch.ErrBlocked):
+		return "blocked"
+	case strings.Contains(msg, "代理池为空"):
+		return "proxy_pool_empty"
+	case strings.Contains(msg, "无效的响应状态码"):
+		return "bad_status"
+	case strings.Contains(msg, "解析 HTML"):
+		return "parse"
+	case strings.Contains(msg, "请求失败"), strings.Contains(msg, "所有重试都失败"):
+		return "request"
+	default:
+		return "other"
+	}
+}
+
 // 辅助函数：从链接中提取帖子ID
 func extractPostID(link string) string {
 	// 假设链接格式为 https://www.chiphell.com/thread-2646639-1-1.html
@@ -378,31 +424,58 @@ func extractPostID(link string) string {
 	return ""
 }
 
-// 处理通知的辅助方法
-func (c *ChiphellMonitor) processNotification(title, message string) {
-	// 收集所有关注该帖子的手机号
+// matchKeywords 返回标题命中关键词的手机号，以及命中的关键词（小写去重，用作商品标识）
+func (c *ChiphellMonitor) matchKeywords(title string) ([]string, []string) {
 	var phoneNumbers []string
+	var matchedKeywords []string
+	seen := make(map[string]bool)
 
-	// 遍历用户的关键词进行匹配
+	lowerTitle := strings.ToLower(title)
 	for phoneNumber, keywords := range c.UserKeywords {
+		matched := false
 		for _, keyword := range keywords {
-			lowerKeyword := strings.ToLower(keyword)
-			lowerTitle := strings.ToLower(title)
-			if strings.Contains(lowerTitle, lowerKeyword) {
+			lowerKeyword := strings.ToLower(strings.TrimSpace(keyword))
+			if lowerKeyword == "" || !strings.Contains(lowerTitle, lowerKeyword) {
+				continue
+			}
+			if !matched {
 				mylog.Debug(fmt.Sprintf("标题 '%s' 匹配到关键词 '%s'，将@手机号 %s", title, keyword, phoneNumber))
 				phoneNumbers = append(phoneNumbers, phoneNumber)
-				break
+				matched = true
+			}
+			if !seen[lowerKeyword] {
+				seen[lowerKeyword] = true
+				matchedKeywords = append(matchedKeywords, lowerKeyword)
 			}
 		}
 	}
-	
+	return phoneNumbers, matchedKeywords
+}
+
+// 处理通知的辅助方法
+func (c *ChiphellMonitor) processNotification(postID, title, message string, detail *PostDetail) {
+	// 收集所有关注该帖子的手机号
+	phoneNumbers, keywords := c.matchKeywords(title)
+
 	// 记录匹配结果
 	if len(phoneNumbers) > 0 {
 		mylog.Debug(fmt.Sprintf("帖子 '%s' 匹配到 %d 个手机号需要@", title, len(phoneNumbers)))
+		for _, phoneNumber := range phoneNumbers {
+			metrics.KeywordMatches.WithLabelValues(c.userLabel(phoneNumber)).Inc()
+		}
+		c.Database.MarkPostMatched(c.ForumName, postID)
+		c.trackThread(postID, title, detail)
 	} else {
 		mylog.Debug(fmt.Sprintf("帖子 '%s' 没有匹配到任何关键词", title))
 	}
 
+	// 附加价格行情
+	if detail != nil {
+		for _, line := range c.priceAnnotations(postID, title, detail.Price, keywords) {
+			message += "\n\n" + line
+		}
+	}
+
 	// 发送通知
 	if len(phoneNumbers) > 0 {
 		c.enqueueNotification(title, message, phoneNumbers)
@@ -411,20 +484,49 @@ func (c *ChiphellMonitor) processNotification(title, message string) {
 	}
 }
 
-func (c *ChiphellMonitor) MonitorPage() {
+// userLabel 返回指标中代表用户的标签，避免手机号出现在指标中。优先使用配置的别名，
+// 否则按手机号排序后的序号生成 user1、user2 这样的标签，配置不变时保持稳定
+func (c *ChiphellMonitor) userLabel(phoneNumber string) string {
+	if alias := c.UserAliases[phoneNumber]; alias != "" {
+		return alias
+	}
+
+	phoneNumbers := make([]string, 0, len(c.UserKeywords))
+	for p := range c.UserKeywords {
+		phoneNumbers = append(phoneNumbers, p)
+	}
+	sort.Strings(phoneNumbers)
+	for i, p := range phoneNumbers {
+		if p == phoneNumber {
+			return fmt.Sprintf("user%d", i+1)
+		}
+	}
+	return "unknown"
+}
+
+// MonitorPage 循环监控列表页，ctx 取消后完成当前一轮处理并返回
+func (c *ChiphellMonitor) MonitorPage(ctx context.Context) {
 	failedAttempts := 0
 	maxFailedAttempts := 3 // 最大连续失败次数
 
-	for {
-		content, err := c.FetchPageContent()
+	for ctx.Err() == nil {
+		metrics.FetchAttempts.Inc()
+		begin := time.Now()
+		content, err := c.FetchPageContent(ctx)
+		metrics.FetchDuration.Observe(time.Since(begin).Seconds())
 		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
 			failedAttempts++
+			c.recordFetch(err, 0)
+			metrics.FetchFailures.WithLabelValues(fetchFailureReason(err)).Inc()
 			mylog.Error(fmt.Sprintf("获取页面内容失败: %v", err))
 
 			// 如果是代理池为空的错误，增加等待时间
 			if strings.Contains(err.Error(), "代理池为空") {
 				mylog.Warn("代理池为空，等待2分钟后重试")
-				time.Sleep(2 * time.Minute)
+				c.waitForNextPoll(ctx, 2 * time.Minute)
 				continue
 			}
 
@@ -432,7 +534,7 @@ func (c *ChiphellMonitor) MonitorPage() {
 			if failedAttempts >= maxFailedAttempts {
 				waitTime := time.Duration(failedAttempts*30) * time.Second
 				mylog.Warn(fmt.Sprintf("连续失败%d次，等待%v后重试", failedAttempts, waitTime))
-				time.Sleep(waitTime)
+				c.waitForNextPoll(ctx, waitTime)
 			}
 			continue
 		}
@@ -441,24 +543,52 @@ func (c *ChiphellMonitor) MonitorPage() {
 		failedAttempts = 0
 
 		posts, err := c.ParseContent(content)
+		guest := errors.Is(err, ErrLoggedOut) && len(posts) > 0
+		if guest {
+			// 游客仍能看到部分帖子：照常处理，同时尝试重新登录，失败时发送告警
+			metrics.FetchFailures.WithLabelValues("logged_out").Inc()
+			if !c.tryRelogin(ctx) {
+				c.reportLoggedOut(err)
+			}
+			err = nil
+		}
+		c.recordFetch(err, len(posts))
+		if errors.Is(err, ErrLoggedOut) {
+			metrics.FetchFailures.WithLabelValues("logged_out").Inc()
+			if c.tryRelogin(ctx) {
+				continue
+			}
+			c.reportLoggedOut(err)
+			c.waitForNextPoll(ctx, c.randomWaitTime())
+			continue
+		}
+		if errors.Is(err, fetch.ErrBlocked) {
+			metrics.FetchFailures.WithLabelValues("blocked").Inc()
+			mylog.Warn(fmt.Sprintf("列表页访问受限: %v", err))
+			c.waitForNextPoll(ctx, c.randomWaitTime())
+			continue
+		}
 		if err != nil {
+			metrics.FetchFailures.WithLabelValues("parse").Inc()
 			mylog.Error("解析页面内容失败", "error", err)
 			c.Notifier.ReportError("解析页面内容失败", err.Error())
 			continue
 		}
 
-		err = c.ProcessPosts(posts)
+		if !guest {
+			c.markLoggedIn()
+		}
+		metrics.PostsParsed.Observe(float64(len(posts)))
+
+		err = c.ProcessPosts(ctx, posts)
 		if err != nil {
 			mylog.Error("处理帖子失败", "error", err)
 			c.Notifier.ReportError("处理帖子失败", err.Error())
 		}
 
 		// 正常处理完毕，等待一段时间后再进行一次监控
-		waitTime := time.Duration(c.WaitTimeRange.Min+rand.Intn(c.WaitTimeRange.Max-c.WaitTimeRange.Min+1)) * time.Second
+		waitTime := c.randomWaitTime()
 		mylog.Debug(fmt.Sprintf("等待 %v 后继续监控", waitTime))
-		time.Sleep(waitTime)
-
-		// 定期清理数据库中过期的帖子
-		// c.Database.CleanUpOldPosts(720 * time.Hour)
+		c.waitForNextPoll(ctx, waitTime)
 	}
 }
@@ -0,0 +1,48 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+)
+
+// HealthStatus 监控健康状态
+type HealthStatus struct {
+	Alive        bool      `json:"alive"`
+	Ready        bool      `json:"ready"`
+	Paused       bool      `json:"paused"`
+	LastNonEmpty time.Time `json:"lastNonEmpty"`
+	Reason       string    `json:"reason,omitempty"`
+}
+
+// Health 根据最近一次解析到帖子的时间判断监控是否正常。
+// 启动后尚未解析到帖子时，以启动时间作为起点计算是否过期
+func (c *ChiphellMonitor) Health(staleAfter time.Duration) HealthStatus {
+	c.state.Lock()
+	defer c.state.Unlock()
+
+	status := HealthStatus{
+		Paused:       c.state.paused,
+		LastNonEmpty: c.state.fetch.LastNonEmpty,
+	}
+
+	since := c.state.fetch.LastNonEmpty
+	if since.IsZero() {
+		since = c.state.startedAt
+	}
+	stale := time.Since(since) > staleAfter
+
+	switch {
+	case status.Paused:
+		status.Alive = true
+		status.Reason = "监控已暂停"
+	case stale:
+		status.Reason = fmt.Sprintf("超过 %v 未成功解析到帖子", staleAfter)
+	case status.LastNonEmpty.IsZero():
+		status.Alive = true
+		status.Reason = "尚未成功解析到帖子"
+	default:
+		status.Alive = true
+		status.Ready = true
+	}
+	return status
+}
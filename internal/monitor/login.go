@@ -0,0 +1,183 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/langchou/informer/pkg/fetch"
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+const (
+	baseURL  = "https://www.chiphell.com/"
+	loginURL = baseURL + "member.php?mod=logging&action=login"
+	listURL  = baseURL + "forum-26-1.html"
+
+	// loginRetryInterval 自动登录失败后的最小重试间隔，避免触发论坛的密码错误次数限制
+	loginRetryInterval = 15 * time.Minute
+)
+
+// Account 论坛账号，用于自动登录刷新 cookies
+type Account struct {
+	Username   string
+	Password   string
+	QuestionID int // 安全提问编号，0 表示未设置
+	Answer     string
+}
+
+// Configured 返回是否配置了账号
+func (a Account) Configured() bool {
+	return a.Username != "" && a.Password != ""
+}
+
+// SeedCookies 将配置中的 Cookie 头导入 cookie jar
+func (c *ChiphellMonitor) SeedCookies(header string) {
+	u, _ := url.Parse(baseURL)
+	c.Jar.Seed(u, header)
+}
+
+// HasCookies 返回 cookie jar 中是否已有论坛的 cookies
+func (c *ChiphellMonitor) HasCookies() bool {
+	u, _ := url.Parse(baseURL)
+	return len(c.Jar.Cookies(u)) > 0
+}
+
+// tryRelogin 在配置了账号且距离上次尝试超过 loginRetryInterval 时自动登录，返回是否登录成功
+func (c *ChiphellMonitor) tryRelogin(ctx context.Context) bool {
+	if !c.Account.Configured() {
+		return false
+	}
+
+	c.state.Lock()
+	last := c.state.lastLoginAttempt
+	if !last.IsZero() && time.Since(last) < loginRetryInterval {
+		c.state.Unlock()
+		return false
+	}
+	c.state.lastLoginAttempt = time.Now()
+	c.state.Unlock()
+
+	if err := c.Login(ctx); err != nil {
+		mylog.Error(fmt.Sprintf("自动登录失败: %v", err))
+		return false
+	}
+	return true
+}
+
+// Login 使用配置的账号走 Discuz 登录流程，登录得到的 cookies 写入共享的 cookie jar 并保存
+func (c *ChiphellMonitor) Login(ctx context.Context) error {
+	if !c.Account.Configured() {
+		return fmt.Errorf("未配置论坛账号")
+	}
+
+	client := c.Client
+
+	// 获取登录表单中的 formhash 和提交地址
+	doc, err := getDocument(ctx, client, loginURL)
+	if err != nil {
+		return fmt.Errorf("获取登录页面失败: %v", err)
+	}
+
+	form := doc.Find("form[name='login']").First()
+	formhash, _ := form.Find("input[name='formhash']").Attr("value")
+	if formhash == "" {
+		formhash, _ = doc.Find("input[name='formhash']").First().Attr("value")
+	}
+	if formhash == "" {
+		return fmt.Errorf("登录页面中未找到 formhash")
+	}
+
+	action, _ := form.Attr("action")
+	if action == "" {
+		action = "member.php?mod=logging&action=login&loginsubmit=yes"
+	}
+	submitURL, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	submitURL, err = submitURL.Parse(action)
+	if err != nil {
+		return fmt.Errorf("登录表单地址无效: %v", err)
+	}
+	query := submitURL.Query()
+	query.Set("inajax", "1")
+	submitURL.RawQuery = query.Encode()
+
+	values := url.Values{
+		"formhash":   {formhash},
+		"referer":    {baseURL},
+		"loginfield": {"username"},
+		"username":   {c.Account.Username},
+		"password":   {c.Account.Password},
+		"questionid": {strconv.Itoa(c.Account.QuestionID)},
+		"answer":     {c.Account.Answer},
+		"cookietime": {"2592000"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", submitURL.String(), strings.NewReader(values.Encode()))
+	if err != nil {
+		return fmt.Errorf("创建登录请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Referer", loginURL)
+
+	// 会话过期后旧的 _auth cookie 通常仍在 jar 中，登录成功的依据是 _auth 被重新下发
+	previousAuth := c.authCookies()
+	resp, err := client.Do(req, "")
+	if err != nil {
+		return fmt.Errorf("登录请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result, err := goquery.NewDocumentFromReader(fetch.DecodeBody(resp))
+	if err != nil {
+		return fmt.Errorf("解析登录响应失败: %v", err)
+	}
+
+	// 登录成功后论坛会下发新的 <前缀>_auth cookie
+	loggedIn := false
+	for name, value := range c.authCookies() {
+		if value != previousAuth[name] {
+			loggedIn = true
+		}
+	}
+	if !loggedIn {
+		message := strings.TrimSpace(result.Text())
+		if len([]rune(message)) > 100 {
+			message = string([]rune(message)[:100])
+		}
+		return fmt.Errorf("登录未成功: %s", message)
+	}
+
+	if err := c.Jar.Save(); err != nil {
+		mylog.Warn(err.Error())
+	}
+	mylog.Info(fmt.Sprintf("账号 %s 自动登录成功", c.Account.Username))
+	return nil
+}
+
+// authCookies 返回 jar 中论坛的 <前缀>_auth cookies，key 为 cookie 名称
+func (c *ChiphellMonitor) authCookies() map[string]string {
+	u, _ := url.Parse(baseURL)
+	cookies := make(map[string]string)
+	for _, cookie := range c.Jar.Cookies(u) {
+		if strings.HasSuffix(cookie.Name, "_auth") && cookie.Value != "" {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+	return cookies
+}
+
+func getDocument(ctx context.Context, client *fetch.Client, targetURL string) (*goquery.Document, error) {
+	content, err := client.GetHTML(ctx, targetURL, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return goquery.NewDocumentFromReader(strings.NewReader(content))
+}
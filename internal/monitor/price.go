@@ -0,0 +1,85 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/util"
+)
+
+// PriceTracking 价格历史及好价检测配置
+type PriceTracking struct {
+	Enabled        bool
+	Window         time.Duration // 统计历史价格的时间窗口
+	MinSamples     int           // 至少需要多少条历史价格才给出比较
+	DealPercentile float64       // 低于该分位数（0-100）的价格标记为好价
+}
+
+// priceAnnotations 记录帖子价格并与同一关键词的历史价格比较，返回需要附加到通知中的行
+func (c *ChiphellMonitor) priceAnnotations(postID, title, priceText string, keywords []string) []string {
+	if !c.PriceTracking.Enabled || len(keywords) == 0 {
+		return nil
+	}
+
+	price, ok := util.ParsePrice(priceText)
+	if !ok {
+		return nil
+	}
+
+	windowDays := int(c.PriceTracking.Window.Hours() / 24)
+	var lines []string
+	for _, key := range keywords {
+		history, err := c.Database.RecentPrices(key, c.PriceTracking.Window, postID)
+		if err != nil {
+			mylog.Error(fmt.Sprintf("查询关键词 %s 历史价格失败: %v", key, err))
+			continue
+		}
+
+		if err := c.Database.RecordPrice(c.ForumName, key, postID, title, price); err != nil {
+			mylog.Error(fmt.Sprintf("记录关键词 %s 价格失败: %v", key, err))
+		}
+
+		if len(history) < c.PriceTracking.MinSamples || len(history) == 0 {
+			continue
+		}
+
+		sort.Float64s(history)
+		median := percentile(history, 50)
+		diff := (price - median) / median * 100
+		switch {
+		case math.Abs(diff) < 0.5:
+			lines = append(lines, fmt.Sprintf("行情: [%s] 与近%d天中位价持平（%d条记录）", key, windowDays, len(history)))
+		case diff < 0:
+			lines = append(lines, fmt.Sprintf("行情: [%s] 比近%d天中位价低 %.0f%%（%d条记录）", key, windowDays, -diff, len(history)))
+		default:
+			lines = append(lines, fmt.Sprintf("行情: [%s] 比近%d天中位价高 %.0f%%（%d条记录）", key, windowDays, diff, len(history)))
+		}
+
+		threshold := percentile(history, c.PriceTracking.DealPercentile)
+		if price <= threshold {
+			lines = append(lines, fmt.Sprintf("好价: [%s] 低于近%d天 %.0f%% 分位价 %.0f", key, windowDays, c.PriceTracking.DealPercentile, threshold))
+			mylog.Info(fmt.Sprintf("帖子 '%s' 价格 %.0f 低于关键词 %s 的 %.0f%% 分位价 %.0f", title, price, key, c.PriceTracking.DealPercentile, threshold))
+		}
+	}
+	return lines
+}
+
+// percentile 计算已排序数据的分位数（线性插值），p 取值 0-100
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 1 {
+		return sorted[0]
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	if lower < 0 {
+		lower = 0
+	}
+	if upper >= len(sorted) {
+		upper = len(sorted) - 1
+	}
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
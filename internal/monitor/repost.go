@@ -0,0 +1,77 @@
+package monitor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/util"
+)
+
+// RepostDetection 重新发布检测配置
+type RepostDetection struct {
+	Enabled  bool
+	Window   time.Duration // 在该时间窗口内出现的相同指纹视为重新发布
+	Suppress bool          // true 时不再通知重新发布的帖子，否则在通知中标记
+}
+
+var uidRe = regexp.MustCompile(`uid[-=](\d+)`)
+
+// extractUID 从用户空间链接中提取 UID，例如 space-uid-12345.html 或 home.php?mod=space&uid=12345
+func extractUID(href string) string {
+	match := uidRe.FindStringSubmatch(href)
+	if len(match) < 2 {
+		return ""
+	}
+	return match[1]
+}
+
+// normalizeTitle 去除标题中的空白、标点与大小写差异，只保留文字和数字
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// postFingerprint 根据卖家 UID、规范化后的标题和价格生成内容指纹，缺少卖家 UID 时返回空字符串
+func postFingerprint(sellerUID, title, priceText string) string {
+	if sellerUID == "" {
+		return ""
+	}
+
+	price := strings.TrimSpace(priceText)
+	if value, ok := util.ParsePrice(priceText); ok {
+		price = fmt.Sprintf("%.0f", value)
+	}
+	return util.HashString(sellerUID + "|" + normalizeTitle(title) + "|" + price)
+}
+
+// checkRepost 记录帖子指纹，并返回同一卖家在检测窗口内发布过的相同帖子ID
+func (c *ChiphellMonitor) checkRepost(post Post, postID, priceText string) string {
+	fingerprint := postFingerprint(post.SellerUID, post.Title, priceText)
+	if fingerprint == "" {
+		return ""
+	}
+	c.Database.SetPostFingerprint(c.ForumName, postID, post.SellerUID, fingerprint)
+
+	if !c.RepostDetection.Enabled {
+		return ""
+	}
+
+	originalID, err := c.Database.FindRepost(c.ForumName, postID, fingerprint, c.RepostDetection.Window)
+	if err != nil {
+		mylog.Error(fmt.Sprintf("检测重新发布失败: %v", err))
+		return ""
+	}
+	if originalID != "" {
+		mylog.Info(fmt.Sprintf("帖子 %s 与卖家 %s 的帖子 %s 内容相同，判定为重新发布", postID, post.SellerUID, originalID))
+	}
+	return originalID
+}
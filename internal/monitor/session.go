@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/langchou/informer/pkg/fetch"
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+// ErrLoggedOut 页面显示为未登录状态，通常是 cookies 已失效
+var ErrLoggedOut = errors.New("未登录或 Cookie 已失效")
+
+var discuzUIDRe = regexp.MustCompile(`discuz_uid\s*=\s*'(\d*)'`)
+
+// 仅会员可见或需要登录时论坛显示的提示
+var loginRequiredNotices = []string{
+	"您需要登录后才可以",
+	"您尚未登录",
+	"只有会员",
+	"请先登录",
+}
+
+// 当前 IP 被限制访问或需要验证时论坛显示的提示
+var blockedNotices = []string{
+	"访问受限",
+	"IP 地址",
+	"IP地址",
+	"访问过于频繁",
+	"刷新过于频繁",
+	"验证码",
+}
+
+// CDN 人机验证页面的特征
+var challengeMarkers = []string{
+	"cf-browser-verification",
+	"challenge-platform",
+	"<title>Just a moment",
+	"<title>Access Denied",
+}
+
+// detectBlocked 判断页面是否为访问受限、频率限制或验证码页面，返回判断依据
+func detectBlocked(doc *goquery.Document, content string) (bool, string) {
+	notice := doc.Find("title, #messagetext, .alert_info, .alert_error").Text()
+	for _, text := range blockedNotices {
+		if strings.Contains(notice, text) {
+			return true, fmt.Sprintf("页面提示: %s", text)
+		}
+	}
+	for _, marker := range challengeMarkers {
+		if strings.Contains(content, marker) {
+			return true, fmt.Sprintf("页面包含 %s", marker)
+		}
+	}
+	return false, ""
+}
+
+// checkListing 校验列表页是否可用，只排除访问受限和验证码页面；
+// 未登录的页面视为可用，由 ParseContent 判断登录状态
+func checkListing(content string) error {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("解析 HTML 失败: %v", err)
+	}
+	if doc.Find("tbody[id^='normalthread_']").Length() > 0 {
+		return nil
+	}
+	if blocked, reason := detectBlocked(doc, content); blocked {
+		return fmt.Errorf("%w: %s", fetch.ErrBlocked, reason)
+	}
+	return nil
+}
+
+// detectLoggedOut 判断页面是否处于未登录状态，返回判断依据
+func detectLoggedOut(doc *goquery.Document, content string, threadCount int) (bool, string) {
+	// 页面中的 discuz_uid 可以直接反映登录状态
+	match := discuzUIDRe.FindStringSubmatch(content)
+	if match != nil {
+		if uid := match[1]; uid != "" && uid != "0" {
+			return false, ""
+		}
+		return true, "discuz_uid 为 0"
+	}
+
+	if doc.Find("#lsform, form[name='login']").Length() > 0 {
+		return true, "页面包含登录表单"
+	}
+
+	notice := doc.Find("#messagetext, .alert_info, .alert_error").Text()
+	for _, text := range loginRequiredNotices {
+		if strings.Contains(notice, text) {
+			return true, fmt.Sprintf("页面提示: %s", text)
+		}
+	}
+
+	if threadCount == 0 {
+		return true, "未解析到帖子且页面缺少 discuz_uid"
+	}
+	return false, ""
+}
+
+// reportLoggedOut 发送 Cookie 失效告警，两次告警之间至少间隔 CookieAlertInterval
+func (c *ChiphellMonitor) reportLoggedOut(err error) {
+	c.state.Lock()
+	last := c.state.lastCookieAlert
+	shouldAlert := last.IsZero() || time.Since(last) >= c.CookieAlertInterval
+	if shouldAlert {
+		c.state.lastCookieAlert = time.Now()
+	}
+	c.state.loggedOut = true
+	c.state.Unlock()
+
+	if !shouldAlert {
+		mylog.Debug(fmt.Sprintf("Cookie 失效告警已在 %v 发送过，跳过", last.Format(time.DateTime)))
+		return
+	}
+
+	mylog.Error(fmt.Sprintf("检测到未登录状态: %v", err))
+	detail := fmt.Sprintf("%v，请更新配置中的 cookies", err)
+	if c.Account.Configured() {
+		detail = fmt.Sprintf("%v，自动登录失败，请检查账号配置或手动更新 cookies", err)
+	}
+	if reportErr := c.Notifier.ReportError("Cookie 已失效", detail); reportErr != nil {
+		mylog.Error(fmt.Sprintf("发送 Cookie 失效告警失败: %v", reportErr))
+	}
+}
+
+// markLoggedIn 解析成功后清除未登录状态。登录状态持续 CookieAlertInterval 后才重置告警时间，
+// 避免登录状态反复变化时频繁告警
+func (c *ChiphellMonitor) markLoggedIn() {
+	c.state.Lock()
+	wasLoggedOut := c.state.loggedOut
+	c.state.loggedOut = false
+	if wasLoggedOut {
+		c.state.loggedInSince = time.Now()
+	} else if !c.state.lastCookieAlert.IsZero() && time.Since(c.state.loggedInSince) >= c.CookieAlertInterval {
+		c.state.lastCookieAlert = time.Time{}
+	}
+	c.state.Unlock()
+
+	if wasLoggedOut {
+		mylog.Info("登录状态已恢复")
+	}
+}
@@ -0,0 +1,172 @@
+package monitor
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FetchStatus 最近一次抓取列表页的结果
+type FetchStatus struct {
+	LastAttempt time.Time `json:"lastAttempt"`
+	LastSuccess time.Time `json:"lastSuccess"`
+	// LastNonEmpty 最近一次解析到帖子（数量大于 0）的时间
+	LastNonEmpty time.Time `json:"lastNonEmpty"`
+	LastError    string    `json:"lastError,omitempty"`
+	LastPosts    int       `json:"lastPosts"`
+	Failures     int       `json:"consecutiveFailures"`
+	// DirectBlockedUntil 直连返回 403/429 后暂停直连的截止时间
+	DirectBlockedUntil time.Time `json:"directBlockedUntil,omitempty"`
+}
+
+// OutboxStatus 通知队列状态
+type OutboxStatus struct {
+	Queued    int       `json:"queued"`  // 队列中尚未取出的消息数
+	Pending   int       `json:"pending"` // 已取出、等待合并发送的消息数
+	Sent      int       `json:"sent"`
+	Failed    int       `json:"failed"`
+	LastSent  time.Time `json:"lastSent"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+// runtimeState 监控器运行状态，供管理接口查询和控制
+type runtimeState struct {
+	sync.Mutex
+	paused    bool
+	forcePoll bool
+	fetch     FetchStatus
+	outbox    OutboxStatus
+	wakeup    chan struct{}
+	startedAt time.Time
+
+	loggedOut        bool
+	loggedInSince    time.Time // 最近一次从未登录状态恢复的时间
+	lastCookieAlert  time.Time
+	lastLoginAttempt time.Time
+}
+
+func newRuntimeState() *runtimeState {
+	return &runtimeState{wakeup: make(chan struct{}, 1), startedAt: time.Now()}
+}
+
+// notify 唤醒正在等待的监控循环
+func (s *runtimeState) notify() {
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Pause 暂停监控，暂停期间仍可通过 TriggerPoll 手动执行一次
+func (c *ChiphellMonitor) Pause() {
+	c.state.Lock()
+	c.state.paused = true
+	c.state.Unlock()
+}
+
+// Resume 恢复监控
+func (c *ChiphellMonitor) Resume() {
+	c.state.Lock()
+	c.state.paused = false
+	c.state.Unlock()
+	c.state.notify()
+}
+
+// Paused 返回监控是否处于暂停状态
+func (c *ChiphellMonitor) Paused() bool {
+	c.state.Lock()
+	defer c.state.Unlock()
+	return c.state.paused
+}
+
+// TriggerPoll 跳过当前等待，立即执行一次监控
+func (c *ChiphellMonitor) TriggerPoll() {
+	c.state.Lock()
+	c.state.forcePoll = true
+	c.state.Unlock()
+	c.state.notify()
+}
+
+// FetchStatus 返回最近一次抓取的结果
+func (c *ChiphellMonitor) FetchStatus() FetchStatus {
+	c.state.Lock()
+	defer c.state.Unlock()
+	return c.state.fetch
+}
+
+// OutboxStatus 返回通知队列状态
+func (c *ChiphellMonitor) OutboxStatus() OutboxStatus {
+	c.state.Lock()
+	defer c.state.Unlock()
+	status := c.state.outbox
+	status.Queued = len(c.MessageQueue)
+	return status
+}
+
+// waitForNextPoll 等待 d 时间，期间收到手动触发或恢复时提前返回；暂停时一直等待直到恢复或手动触发。
+// ctx 取消时立即返回
+func (c *ChiphellMonitor) waitForNextPoll(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-timer.C:
+	case <-c.state.wakeup:
+	}
+
+	for {
+		c.state.Lock()
+		paused, force := c.state.paused, c.state.forcePoll
+		c.state.forcePoll = false
+		c.state.Unlock()
+
+		if !paused || force {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.state.wakeup:
+		}
+	}
+}
+
+func (c *ChiphellMonitor) recordFetch(err error, posts int) {
+	c.state.Lock()
+	defer c.state.Unlock()
+
+	c.state.fetch.LastAttempt = time.Now()
+	if err != nil {
+		c.state.fetch.LastError = err.Error()
+		c.state.fetch.Failures++
+		return
+	}
+	c.state.fetch.LastSuccess = c.state.fetch.LastAttempt
+	c.state.fetch.LastError = ""
+	c.state.fetch.LastPosts = posts
+	if posts > 0 {
+		c.state.fetch.LastNonEmpty = c.state.fetch.LastAttempt
+	}
+	c.state.fetch.Failures = 0
+}
+
+func (c *ChiphellMonitor) recordOutbox(pending int, sent int, err error) {
+	c.state.Lock()
+	defer c.state.Unlock()
+
+	c.state.outbox.Pending = pending
+	if sent == 0 {
+		return
+	}
+	if err != nil {
+		c.state.outbox.Failed += sent
+		c.state.outbox.LastError = err.Error()
+		return
+	}
+	c.state.outbox.Sent += sent
+	c.state.outbox.LastSent = time.Now()
+	c.state.outbox.LastError = ""
+}
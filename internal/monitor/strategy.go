@@ -0,0 +1,119 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/langchou/informer/pkg/fetch"
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+// FetchStrategy 请求论坛页面时直连与代理的使用方式
+type FetchStrategy string
+
+const (
+	StrategyProxyOnly   FetchStrategy = "proxy-only"
+	StrategyDirectOnly  FetchStrategy = "direct-only"
+	StrategyDirectFirst FetchStrategy = "direct-first-then-proxy"
+	StrategyProxyFirst  FetchStrategy = "proxy-first-then-direct"
+
+	// defaultDirectCooldown 直连返回 403/429 后改用代理的时长
+	defaultDirectCooldown = 30 * time.Minute
+)
+
+// strategy 返回生效的抓取策略，没有配置代理来源时只能直连
+func (c *ChiphellMonitor) strategy() FetchStrategy {
+	if !c.Pool.Enabled() {
+		return StrategyDirectOnly
+	}
+	if c.Strategy == "" {
+		return StrategyProxyOnly
+	}
+	return c.Strategy
+}
+
+// fetchPage 按抓取策略请求页面。validate 不为空时校验页面内容，访问受限的代理进入冷却期、直连暂停使用；
+// 开启并发竞速时代理请求同时发往多个代理
+func (c *ChiphellMonitor) fetchPage(ctx context.Context, targetURL string, validate func(content string) error) (string, error) {
+	switch c.strategy() {
+	case StrategyDirectOnly:
+		return c.fetchDirect(ctx, targetURL, validate)
+
+	case StrategyDirectFirst:
+		// 直连暂停期间代理池为空时仍然直连，与代理优先策略在代理池为空时的处理一致
+		if !c.directBlocked() || c.Pool.Count() == 0 {
+			content, err := c.fetchDirect(ctx, targetURL, validate)
+			if err == nil || ctx.Err() != nil || c.Pool.Count() == 0 {
+				return content, err
+			}
+			mylog.Warn(fmt.Sprintf("直连请求失败，改用代理: %v", err))
+		}
+		return c.fetchViaProxies(ctx, targetURL, validate)
+
+	case StrategyProxyFirst:
+		if c.Pool.Count() > 0 {
+			content, err := c.fetchViaProxies(ctx, targetURL, validate)
+			if err == nil || ctx.Err() != nil || c.directBlocked() {
+				return content, err
+			}
+			mylog.Warn(fmt.Sprintf("代理请求失败，改用直连: %v", err))
+		}
+		return c.fetchDirect(ctx, targetURL, validate)
+
+	default:
+		return c.fetchViaProxies(ctx, targetURL, validate)
+	}
+}
+
+// fetchViaProxies 通过代理请求页面。RaceProxies 大于 1 时同时请求多个代理并采用最先返回的有效页面，
+// 否则逐个代理重试
+func (c *ChiphellMonitor) fetchViaProxies(ctx context.Context, targetURL string, validate func(content string) error) (string, error) {
+	if c.RaceProxies > 1 && validate != nil {
+		return c.Client.RaceWithProxies(ctx, c.Pool, targetURL, nil, c.RaceProxies, validate)
+	}
+	return c.Client.FetchWithProxies(ctx, c.Pool, targetURL, nil, validate)
+}
+
+// fetchDirect 直连请求页面。返回 403/429 或访问受限页面说明本机 IP 被限制，一段时间内优先使用代理
+func (c *ChiphellMonitor) fetchDirect(ctx context.Context, targetURL string, validate func(content string) error) (string, error) {
+	content, err := c.Client.GetHTML(ctx, targetURL, "", nil)
+	if err == nil && validate != nil {
+		if err = validate(content); err != nil {
+			content = ""
+		}
+	}
+
+	var statusErr *fetch.StatusError
+	switch {
+	case errors.As(err, &statusErr) && (statusErr.Code == http.StatusForbidden || statusErr.Code == http.StatusTooManyRequests):
+		c.blockDirect(fmt.Sprintf("直连返回 %d", statusErr.Code))
+	case errors.Is(err, fetch.ErrBlocked):
+		c.blockDirect("直连访问受限")
+	}
+	return content, err
+}
+
+// blockDirect 本机 IP 被限制，DirectCooldown 内混合策略改用代理
+func (c *ChiphellMonitor) blockDirect(reason string) {
+	cooldown := c.DirectCooldown
+	if cooldown <= 0 {
+		cooldown = defaultDirectCooldown
+	}
+	c.state.Lock()
+	c.state.fetch.DirectBlockedUntil = time.Now().Add(cooldown)
+	c.state.Unlock()
+
+	if c.strategy() != StrategyDirectOnly {
+		mylog.Warn(fmt.Sprintf("%s，%s 内改用代理", reason, cooldown))
+	}
+}
+
+// directBlocked 直连是否因 IP 被限制暂停使用
+func (c *ChiphellMonitor) directBlocked() bool {
+	c.state.Lock()
+	defer c.state.Unlock()
+	return time.Now().Before(c.state.fetch.DirectBlockedUntil)
+}
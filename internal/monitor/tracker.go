@@ -0,0 +1,144 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/langchou/informer/db"
+	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/util"
+	"golang.org/x/exp/rand"
+)
+
+// ThreadTracking 命中帖子的回访配置
+type ThreadTracking struct {
+	Enabled     bool
+	Duration    time.Duration // 帖子首次出现后持续回访的时长
+	Interval    time.Duration // 回访间隔
+	SoldMarkers []string      // 标题中出现这些标记时视为已售出
+}
+
+// trackThread 将命中关键词的帖子加入回访列表
+func (c *ChiphellMonitor) trackThread(postID, title string, detail *PostDetail) {
+	if !c.ThreadTracking.Enabled {
+		return
+	}
+
+	var price string
+	if detail != nil {
+		price = detail.Price
+	}
+	link := fmt.Sprintf("https://www.chiphell.com/thread-%s-1-1.html", postID)
+	if err := c.Database.TrackThread(c.ForumName, postID, link, title, price); err != nil {
+		mylog.Error(fmt.Sprintf("加入回访列表失败: %v", err))
+	}
+}
+
+// StartThreadTracker 定期回访命中的帖子，检测降价、售出标记、标题修改和删除
+func (c *ChiphellMonitor) StartThreadTracker(ctx context.Context) {
+	ticker := time.NewTicker(c.ThreadTracking.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.revisitThreads(ctx)
+		}
+	}
+}
+
+func (c *ChiphellMonitor) revisitThreads(ctx context.Context) {
+	threads, err := c.Database.ActiveTrackedThreads(c.ForumName, c.ThreadTracking.Duration)
+	if err != nil {
+		mylog.Error(fmt.Sprintf("获取回访帖子失败: %v", err))
+		return
+	}
+	if len(threads) == 0 {
+		return
+	}
+
+	mylog.Info(fmt.Sprintf("开始回访 %d 个帖子", len(threads)))
+	for i, thread := range threads {
+		if i > 0 {
+			// 回访请求之间随机间隔，避免请求过于密集
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Duration(1+rand.Intn(3)) * time.Second):
+			}
+		}
+		c.revisitThread(ctx, thread)
+	}
+}
+
+func (c *ChiphellMonitor) revisitThread(ctx context.Context, thread db.TrackedThread) {
+	detail, err := c.FetchPostMainContent(ctx, thread.Link)
+	if errors.Is(err, ErrThreadDeleted) {
+		mylog.Info(fmt.Sprintf("帖子已删除: %s %s", thread.Title, thread.Link))
+		c.updateTrackedThread(thread, thread.Title, thread.Price, db.ThreadDeleted)
+		return
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		mylog.Warn(fmt.Sprintf("回访帖子 %s 失败: %v", thread.Link, err))
+		return
+	}
+
+	// 没有标题说明返回的不是帖子页面（登录提示、验证码或访问受限），保留原有记录
+	if detail.Title == "" {
+		mylog.Warn(fmt.Sprintf("回访帖子 %s 未返回帖子内容，跳过本次回访", thread.Link))
+		return
+	}
+
+	title := detail.Title
+	if title != thread.Title {
+		mylog.Info(fmt.Sprintf("帖子标题修改: '%s' -> '%s'", thread.Title, title))
+	}
+
+	// 新价格无法解析时保留原价格，避免之后无法判断降价
+	price := thread.Price
+	newPrice, newOK := util.ParsePrice(detail.Price)
+	if newOK {
+		price = detail.Price
+	}
+
+	if c.isSold(title) {
+		mylog.Info(fmt.Sprintf("帖子已售出: %s %s", title, thread.Link))
+		c.updateTrackedThread(thread, title, price, db.ThreadSold)
+		return
+	}
+
+	oldPrice, oldOK := util.ParsePrice(thread.Price)
+	if oldOK && newOK && newPrice < oldPrice {
+		mylog.Info(fmt.Sprintf("帖子降价: %s %.0f -> %.0f", title, oldPrice, newPrice))
+		phoneNumbers, _ := c.matchKeywords(title)
+		message := fmt.Sprintf("标题: %s\n\n链接: %s\n\n状态: 降价 %s -> %s", title, thread.Link, thread.Price, detail.Price)
+		c.enqueueNotification(title, message, phoneNumbers)
+	}
+
+	c.updateTrackedThread(thread, title, price, db.ThreadActive)
+}
+
+func (c *ChiphellMonitor) updateTrackedThread(thread db.TrackedThread, title, price, status string) {
+	if err := c.Database.UpdateTrackedThread(c.ForumName, thread.PostID, title, price, status); err != nil {
+		mylog.Error(fmt.Sprintf("更新回访帖子失败: %v", err))
+	}
+}
+
+// isSold 判断标题中是否包含售出标记
+func (c *ChiphellMonitor) isSold(title string) bool {
+	lowerTitle := strings.ToLower(title)
+	for _, marker := range c.ThreadTracking.SoldMarkers {
+		if marker != "" && strings.Contains(lowerTitle, strings.ToLower(marker)) {
+			return true
+		}
+	}
+	return false
+}
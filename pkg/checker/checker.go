@@ -2,73 +2,111 @@ package checker
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"net"
+	"io"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
+	"github.com/langchou/informer/pkg/fetch"
 	mylog "github.com/langchou/informer/pkg/log"
-	customproxy "golang.org/x/net/proxy"
+	"github.com/langchou/informer/pkg/metrics"
+	"github.com/langchou/informer/pkg/proxy"
 )
 
-func CheckIP(proxyIP string) (bool, float64) {
-	ProcessedProxyIP := strings.Replace(proxyIP, "socks5://", "", 1)
-	pollURL := "http://ipinfo.io"
-	begin := time.Now()
+// DefaultCheckURL 未配置检测目标时使用的地址，只要求返回 200
+const DefaultCheckURL = "http://ipinfo.io"
 
-	// 解析代理URL
-	proxyURL, err := url.Parse(proxyIP)
-	if err != nil {
-		mylog.Debug(fmt.Sprintf("解析代理URL失败: %v", err))
-		return false, 0
-	}
+// Target 代理检测目标。设置 Selector、Forbidden 或 Validate 时会解析页面内容：
+// 页面中必须存在 Selector 匹配的元素，不能包含 Forbidden 中的任何文本，且 Validate 不返回错误
+type Target struct {
+	URL       string
+	Selector  string
+	Forbidden []string
+	// Validate 自定义页面校验，例如只在页面没有正常内容时才检查限制访问的特征
+	Validate func(content string) error
+}
 
-	// 创建 SOCKS5 拨号器
-	dialer, err := customproxy.SOCKS5("tcp", proxyURL.Host, nil, customproxy.Direct)
-	if err != nil {
-		mylog.Debug(fmt.Sprintf("创建SOCKS5拨号器失败: %v", err))
-		return false, 0
+// hasAssertions 是否需要检查页面内容
+func (t Target) hasAssertions() bool {
+	return t.Selector != "" || len(t.Forbidden) > 0 || t.Validate != nil
+}
+
+// CheckIP 使用默认客户端检测代理，只要求 DefaultCheckURL 返回 200
+func CheckIP(proxyIP string) (bool, float64) {
+	return New(proxy.Default(), fetch.Default(), Target{}).CheckIP(context.Background(), proxyIP)
+}
+
+// CheckIP 通过代理请求检测目标，返回是否可用及响应时间（毫秒）
+func (c *Checker) CheckIP(ctx context.Context, proxyIP string) (bool, float64) {
+	valid, responseTime := c.checkIP(ctx, proxyIP)
+	if valid {
+		metrics.ProxyChecks.WithLabelValues("ok").Inc()
+	} else {
+		metrics.ProxyChecks.WithLabelValues("failed").Inc()
 	}
+	return valid, responseTime
+}
 
-	// 减少超时时间
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return dialer.Dial(network, addr)
-			},
-			TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
-			MaxIdleConnsPerHost: 10,
-		},
+func (c *Checker) checkIP(ctx context.Context, proxyIP string) (bool, float64) {
+	target := c.target
+	if target.URL == "" {
+		target.URL = DefaultCheckURL
 	}
+	displayIP := proxy.Redact(proxyIP)
+	begin := time.Now()
 
 	// 添加请求上下文超时控制
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	request, err := http.NewRequestWithContext(ctx, "GET", pollURL, nil)
-	if err != nil {
-		mylog.Debug(fmt.Sprintf("创建请求失败: %v", err))
+	if err := checkTarget(ctx, c.client, proxyIP, target); err != nil {
+		mylog.Debug(fmt.Sprintf("代理 %s 检测未通过: %v", displayIP, err))
 		return false, 0
 	}
-	request.Header.Add("accept", "text/plain")
 
-	resp, err := client.Do(request)
-	if err != nil {
-		mylog.Debug(fmt.Sprintf("请求失败: %v", err))
-		return false, 0
-	}
-	defer resp.Body.Close()
+	duration := time.Since(begin).Milliseconds()
+	mylog.Debug(fmt.Sprintf("代理 %s 可用, 响应时间: %d ms", displayIP, duration))
+	return true, float64(duration)
+}
 
-	if resp.StatusCode == http.StatusOK {
-		duration := time.Since(begin).Milliseconds()
-		mylog.Debug(fmt.Sprintf("代理 %s 可用, 响应时间: %d ms", ProcessedProxyIP, duration))
-		return true, float64(duration)
+// checkTarget 通过代理请求检测目标，返回不满足要求的原因
+func checkTarget(ctx context.Context, client *fetch.Client, proxyIP string, target Target) error {
+	if !target.hasAssertions() {
+		resp, err := client.Get(ctx, target.URL, proxyIP, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("响应状态码异常: %d", resp.StatusCode)
+		}
+		return nil
 	}
 
-	mylog.Debug(fmt.Sprintf("代理 %s 响应状态码异常: %d", ProcessedProxyIP, resp.StatusCode))
-	return false, 0
+	content, err := client.GetHTML(ctx, target.URL, proxyIP, nil)
+	if err != nil {
+		return err
+	}
+	for _, text := range target.Forbidden {
+		if text != "" && strings.Contains(content, text) {
+			return fmt.Errorf("页面包含 %q", text)
+		}
+	}
+	if target.Selector != "" {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("解析 HTML 失败: %v", err)
+		}
+		if doc.Find(target.Selector).Length() == 0 {
+			return fmt.Errorf("页面中未找到 %s", target.Selector)
+		}
+	}
+	if target.Validate != nil {
+		return target.Validate(content)
+	}
+	return nil
 }
@@ -0,0 +1,176 @@
+package checker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/langchou/informer/pkg/fetch"
+	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/proxy"
+)
+
+const (
+	DefaultInterval    = 5 * time.Minute // 代理检测间隔
+	DefaultConcurrency = 20              // 同时检测的代理数量
+)
+
+// Checker 定期检测一个代理池中的代理，检测通过的标记为优选代理。
+// 使用不同代理来源的监控各自持有 Checker，检测目标和进度互不影响
+type Checker struct {
+	pool        *proxy.Pool
+	client      *fetch.Client
+	target      Target // 为空时只检查 DefaultCheckURL 是否返回 200
+	interval    time.Duration
+	concurrency int
+
+	mu       sync.Mutex
+	progress Progress
+}
+
+// Option 代理检测配置项
+type Option func(*Checker)
+
+// WithInterval 设置检测间隔
+func WithInterval(interval time.Duration) Option {
+	return func(c *Checker) {
+		if interval > 0 {
+			c.interval = interval
+		}
+	}
+}
+
+// WithConcurrency 设置同时检测的代理数量
+func WithConcurrency(concurrency int) Option {
+	return func(c *Checker) {
+		if concurrency > 0 {
+			c.concurrency = concurrency
+		}
+	}
+}
+
+// New 创建检测 pool 中代理的 Checker，client 为空时使用默认客户端
+func New(pool *proxy.Pool, client *fetch.Client, target Target, opts ...Option) *Checker {
+	if client == nil {
+		client = fetch.Default()
+	}
+	c := &Checker{
+		pool:        pool,
+		client:      client,
+		target:      target,
+		interval:    DefaultInterval,
+		concurrency: DefaultConcurrency,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Progress 代理检测进度
+type Progress struct {
+	Running    bool      `json:"running"`
+	Total      int       `json:"total"`
+	Checked    int       `json:"checked"`
+	Valid      int       `json:"valid"`
+	StartedAt  time.Time `json:"startedAt,omitempty"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+}
+
+// Progress 返回当前（或最近一次）检测的进度
+func (c *Checker) Progress() Progress {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.progress
+}
+
+// Start 启动IP检测器，启动时立即检测一次，之后按间隔定期检测
+func (c *Checker) Start(ctx context.Context) {
+	c.checkAllProxies(ctx)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.checkAllProxies(ctx)
+		}
+	}
+}
+
+// checkAllProxies 使用有限数量的 worker 并发检测代理池中的所有代理，包括已有的优选代理
+func (c *Checker) checkAllProxies(ctx context.Context) {
+	proxies := c.pool.Proxies()
+	if len(proxies) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	if c.progress.Running {
+		c.mu.Unlock()
+		mylog.Info("上一轮代理检测尚未结束，跳过本次检测")
+		return
+	}
+	c.progress = Progress{Running: true, Total: len(proxies), StartedAt: time.Now()}
+	c.mu.Unlock()
+
+	mylog.Info(fmt.Sprintf("开始检测代理池中的IP，共 %d 个代理，并发数 %d", len(proxies), c.concurrency))
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for proxyIP := range jobs {
+				c.checkProxy(ctx, proxyIP)
+			}
+		}()
+	}
+
+feed:
+	for _, proxyIP := range proxies {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- proxyIP:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	c.mu.Lock()
+	c.progress.Running = false
+	c.progress.FinishedAt = time.Now()
+	result := c.progress
+	c.mu.Unlock()
+
+	mylog.Info(fmt.Sprintf("IP检测完成，检测 %d 个，可用 %d 个，耗时 %s，当前优选代理数量: %d",
+		result.Checked, result.Valid, result.FinishedAt.Sub(result.StartedAt).Round(time.Second), c.pool.PreferredCount()))
+}
+
+// checkProxy 检测单个代理并更新其优选状态
+func (c *Checker) checkProxy(ctx context.Context, proxyIP string) {
+	valid, responseTime := c.CheckIP(ctx, proxyIP)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if valid {
+		c.pool.SetPreferred(proxyIP, responseTime)
+		mylog.Debug(fmt.Sprintf("添加新的优选代理: %s, 响应时间: %.2fms", proxy.Redact(proxyIP), responseTime))
+	} else {
+		c.pool.Demote(proxyIP)
+	}
+
+	c.mu.Lock()
+	c.progress.Checked++
+	if valid {
+		c.progress.Valid++
+	}
+	c.mu.Unlock()
+}
@@ -23,14 +23,118 @@ type Config struct {
 	} `yaml:"dingtalk"`
 
 	ProxyPoolAPI string `yaml:"proxyPoolAPI"`
-	Cookies      string `yaml:"cookies"`
+	// ProxySources 额外的代理来源，与 ProxyPoolAPI 合并使用
+	ProxySources []ProxySource `yaml:"proxySources"`
+
+	ProxyPool struct {
+		TTLMinutes int     `yaml:"ttlMinutes"` // 代理来源连续多久未返回某代理后将其移出代理池（分钟）
+		MinRatio   float64 `yaml:"minRatio"`   // 来源返回的代理数少于现有数量的该比例时保留现有代理池
+		StateFile  string  `yaml:"stateFile"`  // 保存代理池及评分的文件，重启后从中恢复
+	} `yaml:"proxyPool"`
+
+	ProxyCheck struct {
+		IntervalMinutes int `yaml:"intervalMinutes"` // 代理检测间隔（分钟）
+		Concurrency     int `yaml:"concurrency"`     // 同时检测的代理数量
+		// 检测目标，url 为空时使用监控器默认的目标（论坛列表页）
+		URL       string   `yaml:"url"`
+		Selector  string   `yaml:"selector"`  // 页面中必须存在的元素
+		Forbidden []string `yaml:"forbidden"` // 页面中不能出现的文本
+	} `yaml:"proxyCheck"`
+	Cookies string `yaml:"cookies"`
+
+	// Account 论坛账号，配置后在 cookies 失效时自动登录
+	Account struct {
+		Username   string `yaml:"username"`
+		Password   string `yaml:"password"`
+		QuestionID int    `yaml:"questionId"` // 安全提问编号，0 表示未设置
+		Answer     string `yaml:"answer"`
+	} `yaml:"account"`
+	// CookieFile 持久化 cookie jar 的文件，保存服务器下发的 cookies，重启后继续使用
+	CookieFile string `yaml:"cookieFile"`
+
+	// CookieAlertIntervalMinutes 检测到 Cookie 失效后两次告警之间的最小间隔（分钟）
+	CookieAlertIntervalMinutes int `yaml:"cookieAlertIntervalMinutes"`
+
+	// HTTP 所有请求共用的客户端设置
+	HTTP struct {
+		TimeoutSeconds int    `yaml:"timeoutSeconds"` // 单次请求超时（秒）
+		UserAgent      string `yaml:"userAgent"`
+		// Strategy 直连与代理的使用方式：proxy-only、direct-only、direct-first-then-proxy、proxy-first-then-direct，
+		// 为空时配置了代理来源使用 proxy-only，否则直连
+		Strategy              string `yaml:"strategy"`
+		DirectCooldownMinutes int    `yaml:"directCooldownMinutes"` // 直连返回 403/429 后改用代理的时长（分钟）
+		// RaceProxies 大于 1 时列表页同时通过该数量的代理请求，采用最先返回的有效页面并取消其余请求，0 或 1 为逐个代理重试
+		RaceProxies int `yaml:"raceProxies"`
+		// InsecureProxyTLS 经由代理的请求跳过 TLS 证书校验，直连始终校验
+		InsecureProxyTLS bool `yaml:"insecureProxyTLS"`
+	} `yaml:"http"`
 
 	UserKeyWords map[string][]string `yaml:"userKeyWords"`
+	// UserAliases 手机号对应的别名，用作指标中的 user 标签，未配置时使用 user1、user2 这样的序号
+	UserAliases map[string]string `yaml:"userAliases"`
 
 	WaitTimeRange struct {
 		Min int `yaml:"min"`
 		Max int `yaml:"max"`
 	} `yaml:"waitTimeRange"`
+
+	Retention struct {
+		Enabled       bool `yaml:"enabled"`
+		KeepDays      int  `yaml:"keepDays"`      // 帖子记录保留天数
+		KeepMatched   bool `yaml:"keepMatched"`   // 永久保留命中过关键词的记录
+		IntervalHours int  `yaml:"intervalHours"` // 清理间隔（小时）
+	} `yaml:"retention"`
+
+	PriceHistory struct {
+		Enabled        bool    `yaml:"enabled"`
+		WindowDays     int     `yaml:"windowDays"`     // 统计最近多少天的价格
+		MinSamples     int     `yaml:"minSamples"`     // 至少多少条历史记录才进行比较
+		DealPercentile float64 `yaml:"dealPercentile"` // 低于该分位数（0-100）视为好价
+	} `yaml:"priceHistory"`
+
+	RepostDetection struct {
+		Enabled     bool   `yaml:"enabled"`
+		WindowHours int    `yaml:"windowHours"` // 检测窗口（小时）
+		Action      string `yaml:"action"`      // mark: 标记"重新发布"；suppress: 不再通知
+	} `yaml:"repostDetection"`
+
+	ThreadTracking struct {
+		Enabled         bool     `yaml:"enabled"`
+		Days            int      `yaml:"days"`            // 命中帖子持续回访的天数
+		IntervalMinutes int      `yaml:"intervalMinutes"` // 回访间隔（分钟）
+		SoldMarkers     []string `yaml:"soldMarkers"`     // 标题中的售出标记
+	} `yaml:"threadTracking"`
+
+	Admin struct {
+		Enabled bool   `yaml:"enabled"`
+		Listen  string `yaml:"listen"` // 监听地址，默认 "127.0.0.1:8080"，监听其他地址时必须配置 token
+		Token   string `yaml:"token"`  // 非空时要求请求携带 Authorization: Bearer <token>
+	} `yaml:"admin"`
+
+	Health struct {
+		Enabled      bool `yaml:"enabled"`
+		StaleMinutes int  `yaml:"staleMinutes"` // 超过该时间未解析到帖子视为异常
+	} `yaml:"health"`
+}
+
+// ProxySource 代理来源配置
+type ProxySource struct {
+	Type     string   `yaml:"type"`     // static、file 或 api
+	Proxies  []string `yaml:"proxies"`  // static: 代理地址列表
+	Path     string   `yaml:"path"`     // file: 代理文件路径，每行一个地址
+	URL      string   `yaml:"url"`      // api: 接口地址
+	Format   string   `yaml:"format"`   // api: text 或 json
+	Protocol string   `yaml:"protocol"` // api: 地址未写明协议时使用的协议
+	// JSON api: JSON 响应中代理列表的位置和字段名，路径用 "." 分隔
+	JSON struct {
+		List     string `yaml:"list"`
+		Address  string `yaml:"address"`
+		IP       string `yaml:"ip"`
+		Port     string `yaml:"port"`
+		Protocol string `yaml:"protocol"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+	} `yaml:"json"`
 }
 
 func InitConfig() (*Config, error) {
@@ -49,5 +153,124 @@ func InitConfig() (*Config, error) {
 		return nil, fmt.Errorf("解析配置文件失败: %v", err)
 	}
 
+	applyDefaults(&config)
+	if err := validate(&config); err != nil {
+		return nil, err
+	}
 	return &config, nil
 }
+
+// applyDefaults 为未配置的可选项填充默认值
+func applyDefaults(config *Config) {
+	if config.CookieFile == "" {
+		config.CookieFile = "data/cookies.json"
+	}
+	if config.CookieAlertIntervalMinutes <= 0 {
+		config.CookieAlertIntervalMinutes = 360
+	}
+	for i := range config.ProxySources {
+		source := &config.ProxySources[i]
+		if source.Type == "api" && source.Format == "" {
+			source.Format = "text"
+		}
+		if source.Type == "api" && source.Format == "json" && source.JSON.Address == "" && source.JSON.IP == "" {
+			source.JSON.IP = "ip"
+			source.JSON.Port = "port"
+		}
+	}
+	if config.ProxyPool.TTLMinutes <= 0 {
+		config.ProxyPool.TTLMinutes = 30
+	}
+	if config.ProxyPool.MinRatio <= 0 || config.ProxyPool.MinRatio > 1 {
+		config.ProxyPool.MinRatio = 0.2
+	}
+	if config.ProxyPool.StateFile == "" {
+		config.ProxyPool.StateFile = "data/proxies.json"
+	}
+	if config.ProxyCheck.IntervalMinutes <= 0 {
+		config.ProxyCheck.IntervalMinutes = 5
+	}
+	if config.ProxyCheck.Concurrency <= 0 {
+		config.ProxyCheck.Concurrency = 20
+	}
+	if config.HTTP.TimeoutSeconds <= 0 {
+		config.HTTP.TimeoutSeconds = 10
+	}
+	if config.HTTP.UserAgent == "" {
+		config.HTTP.UserAgent = "Mozilla/5.0"
+	}
+	if config.HTTP.DirectCooldownMinutes <= 0 {
+		config.HTTP.DirectCooldownMinutes = 30
+	}
+	if config.Retention.KeepDays <= 0 {
+		config.Retention.KeepDays = 90
+	}
+	if config.Retention.IntervalHours <= 0 {
+		config.Retention.IntervalHours = 24
+	}
+	if config.PriceHistory.WindowDays <= 0 {
+		config.PriceHistory.WindowDays = 30
+	}
+	if config.PriceHistory.MinSamples <= 0 {
+		config.PriceHistory.MinSamples = 3
+	}
+	if config.PriceHistory.DealPercentile <= 0 || config.PriceHistory.DealPercentile > 100 {
+		config.PriceHistory.DealPercentile = 20
+	}
+	if config.RepostDetection.WindowHours <= 0 {
+		config.RepostDetection.WindowHours = 72
+	}
+	if config.RepostDetection.Action == "" {
+		config.RepostDetection.Action = "mark"
+	}
+	if config.ThreadTracking.Days <= 0 {
+		config.ThreadTracking.Days = 3
+	}
+	if config.ThreadTracking.IntervalMinutes <= 0 {
+		config.ThreadTracking.IntervalMinutes = 30
+	}
+	if len(config.ThreadTracking.SoldMarkers) == 0 {
+		config.ThreadTracking.SoldMarkers = []string{"已出", "已售", "售出", "已卖", "sold"}
+	}
+	if config.Admin.Listen == "" {
+		config.Admin.Listen = "127.0.0.1:8080"
+	}
+	if config.Health.StaleMinutes <= 0 {
+		config.Health.StaleMinutes = 60
+	}
+}
+
+// validate 检查取值有限的配置项
+func validate(config *Config) error {
+	for i, source := range config.ProxySources {
+		switch {
+		case source.Type == "static":
+		case source.Type == "file" && source.Path != "":
+		case source.Type == "api" && source.URL != "":
+			if source.Format != "text" && source.Format != "json" {
+				return fmt.Errorf("proxySources[%d].format 无效: %s（可选 text、json）", i, source.Format)
+			}
+		case source.Type == "file" || source.Type == "api":
+			return fmt.Errorf("proxySources[%d] 缺少 path 或 url", i)
+		default:
+			return fmt.Errorf("proxySources[%d].type 无效: %s（可选 static、file、api）", i, source.Type)
+		}
+	}
+
+	if config.HTTP.RaceProxies < 0 {
+		return fmt.Errorf("http.raceProxies 不能为负数")
+	}
+
+	switch config.HTTP.Strategy {
+	case "", "proxy-only", "direct-only", "direct-first-then-proxy", "proxy-first-then-direct":
+	default:
+		return fmt.Errorf("http.strategy 无效: %s（可选 proxy-only、direct-only、direct-first-then-proxy、proxy-first-then-direct）", config.HTTP.Strategy)
+	}
+
+	switch config.RepostDetection.Action {
+	case "mark", "suppress":
+	default:
+		return fmt.Errorf("repostDetection.action 无效: %s（可选 mark、suppress）", config.RepostDetection.Action)
+	}
+	return nil
+}
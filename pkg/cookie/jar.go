@@ -0,0 +1,302 @@
+package cookie
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/util"
+)
+
+// entry 单个 cookie 的完整信息，用于持久化
+type entry struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HostOnly bool      `json:"hostOnly,omitempty"`
+}
+
+func (e *entry) key() string {
+	return e.Domain + ";" + e.Path + ";" + e.Name
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && !e.Expires.After(now)
+}
+
+// matches 判断 cookie 是否应随该 URL 的请求发送
+func (e *entry) matches(u *url.URL, now time.Time) bool {
+	if e.expired(now) {
+		return false
+	}
+	if e.Secure && u.Scheme != "https" {
+		return false
+	}
+
+	host := canonicalHost(u)
+	if e.HostOnly {
+		if host != e.Domain {
+			return false
+		}
+	} else if host != e.Domain && !strings.HasSuffix(host, "."+e.Domain) {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	return strings.HasPrefix(path, e.Path)
+}
+
+// fileContent cookies 文件格式，Seed 记录最近一次从配置导入的 cookies 的哈希
+type fileContent struct {
+	Seed    string   `json:"seed,omitempty"`
+	Cookies []*entry `json:"cookies"`
+}
+
+// Jar 实现 http.CookieJar，接收服务器下发的 Set-Cookie 并持久化到文件，所有请求共享
+type Jar struct {
+	mu      sync.Mutex
+	path    string
+	seed    string
+	entries map[string]*entry
+	dirty   bool
+}
+
+// NewJar 创建 cookie jar，path 非空时从该文件加载上次保存的 cookies
+func NewJar(path string) (*Jar, error) {
+	j := &Jar{path: path, entries: make(map[string]*entry)}
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取 cookies 文件失败: %v", err)
+	}
+
+	var content fileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("解析 cookies 文件失败: %v", err)
+	}
+
+	now := time.Now()
+	j.seed = content.Seed
+	for _, e := range content.Cookies {
+		if e != nil && !e.expired(now) {
+			j.entries[e.key()] = e
+		}
+	}
+	mylog.Info(fmt.Sprintf("已从 %s 加载 %d 个 cookies", path, len(j.entries)))
+	return j, nil
+}
+
+// Seed 导入配置中的 Cookie 头（如 "a=1; b=2"）。同一份配置只导入一次，
+// 配置变化后重新导入并覆盖同名 cookie，这样手动更新配置中的 cookies 后仍然生效
+func (j *Jar) Seed(u *url.URL, header string) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return
+	}
+
+	seed := util.HashString(header)
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if seed == j.seed {
+		return
+	}
+
+	// 使用去掉 www. 的域名，与论坛下发的 .example.com 域 cookie 合并
+	domain := strings.TrimPrefix(canonicalHost(u), "www.")
+	count := 0
+	for _, part := range strings.Split(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || name == "" {
+			continue
+		}
+		e := &entry{Name: name, Value: value, Domain: domain, Path: "/"}
+		j.entries[e.key()] = e
+		count++
+	}
+	j.seed = seed
+	j.dirty = true
+	mylog.Info(fmt.Sprintf("已从配置导入 %d 个 cookies", count))
+}
+
+// SetCookies 实现 http.CookieJar
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	now := time.Now()
+	host := canonicalHost(u)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		e := &entry{
+			Name:   c.Name,
+			Value:  c.Value,
+			Domain: strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+			Path:   c.Path,
+			Secure: c.Secure,
+		}
+		if e.Domain == "" {
+			e.Domain = host
+			e.HostOnly = true
+		} else if host != e.Domain && !strings.HasSuffix(host, "."+e.Domain) {
+			// 不接受为其他域名设置的 cookie
+			continue
+		}
+		if e.Path == "" || !strings.HasPrefix(e.Path, "/") {
+			e.Path = "/"
+		}
+
+		switch {
+		case c.MaxAge < 0:
+			e.Expires = now
+		case c.MaxAge > 0:
+			e.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		default:
+			e.Expires = c.Expires
+		}
+
+		if e.expired(now) {
+			if _, exists := j.entries[e.key()]; exists {
+				delete(j.entries, e.key())
+				j.dirty = true
+			}
+			continue
+		}
+
+		if old, exists := j.entries[e.key()]; exists && *old == *e {
+			continue
+		}
+		j.entries[e.key()] = e
+		j.dirty = true
+	}
+}
+
+// Cookies 实现 http.CookieJar
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	now := time.Now()
+
+	j.mu.Lock()
+	var matched []*entry
+	for _, e := range j.entries {
+		if e.matches(u, now) {
+			matched = append(matched, e)
+		}
+	}
+	j.mu.Unlock()
+
+	// 路径更长的 cookie 排在前面
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].Path) != len(matched[b].Path) {
+			return len(matched[a].Path) > len(matched[b].Path)
+		}
+		return matched[a].Name < matched[b].Name
+	})
+
+	cookies := make([]*http.Cookie, 0, len(matched))
+	for _, e := range matched {
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value})
+	}
+	return cookies
+}
+
+// Save 将 cookies 写入文件，没有变化时不写入。写入失败时保留未保存标记，下一次保存时重试
+func (j *Jar) Save() (err error) {
+	if j.path == "" {
+		return nil
+	}
+
+	j.mu.Lock()
+	if !j.dirty {
+		j.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	content := fileContent{Seed: j.seed}
+	for _, e := range j.entries {
+		if !e.expired(now) {
+			content.Cookies = append(content.Cookies, e)
+		}
+	}
+	// 写入期间新的 Set-Cookie 会重新标记 dirty，因此在写入前清除；写入失败时恢复
+	j.dirty = false
+	j.mu.Unlock()
+	defer func() {
+		if err != nil {
+			j.mu.Lock()
+			j.dirty = true
+			j.mu.Unlock()
+		}
+	}()
+
+	sort.Slice(content.Cookies, func(a, b int) bool {
+		return content.Cookies[a].key() < content.Cookies[b].key()
+	})
+
+	data, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 cookies 失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o755); err != nil {
+		return fmt.Errorf("创建 cookies 目录失败: %v", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中途退出导致文件损坏
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("保存 cookies 失败: %v", err)
+	}
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("保存 cookies 失败: %v", err)
+	}
+	return nil
+}
+
+// StartAutoSave 定期保存有变化的 cookies，ctx 取消时保存最后一次后返回
+func (j *Jar) StartAutoSave(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := j.Save(); err != nil {
+				mylog.Error(err.Error())
+			}
+			return
+		case <-ticker.C:
+			if err := j.Save(); err != nil {
+				mylog.Error(err.Error())
+			}
+		}
+	}
+}
+
+// canonicalHost 返回去掉端口的小写主机名
+func canonicalHost(u *url.URL) string {
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// sniffLen 检测编码时读取的字节数，与 HTML 规范的 meta 预扫描长度一致
+const sniffLen = 1024
+
+// DecodeBody 根据 Content-Type 响应头、BOM 或页面中的 <meta charset> 检测编码，
+// 返回转码为 UTF-8 的响应内容。goquery 只按 UTF-8 解析，GBK 页面需要先转码
+func DecodeBody(resp *http.Response) io.Reader {
+	return NewUTF8Reader(resp.Body, resp.Header.Get("Content-Type"))
+}
+
+// NewUTF8Reader 将 r 转码为 UTF-8，contentType 为空时仅根据内容检测
+func NewUTF8Reader(r io.Reader, contentType string) io.Reader {
+	br := bufio.NewReaderSize(r, sniffLen)
+	head, _ := br.Peek(sniffLen)
+
+	enc, name, certain := charset.DetermineEncoding(head, contentType)
+	// 没有响应头、BOM 和 <meta charset> 时 DetermineEncoding 默认返回 windows-1252，
+	// 开头较长的 UTF-8 页面前 1024 字节可能全是 ASCII，此时按 UTF-8 原样返回
+	if !certain && name == "windows-1252" && !declaresCharset(head) {
+		return br
+	}
+	enc = widenEncoding(enc)
+	if enc == unicode.UTF8 || enc == encoding.Nop {
+		return br
+	}
+	return transform.NewReader(br, enc.NewDecoder())
+}
+
+// declaresCharset 页面开头是否声明了编码（<meta charset> 或 http-equiv Content-Type）
+func declaresCharset(head []byte) bool {
+	return bytes.Contains(bytes.ToLower(head), []byte("charset"))
+}
+
+// widenEncoding 将 GBK（包括标注为 gb2312 的页面）按 GB18030 解码，GB18030 是 GBK 的超集，
+// 论坛标注 gbk 但实际混入 GB18030 字符时不会出现乱码
+func widenEncoding(enc encoding.Encoding) encoding.Encoding {
+	if enc == simplifiedchinese.GBK {
+		return simplifiedchinese.GB18030
+	}
+	return enc
+}
@@ -0,0 +1,76 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestGetHTMLDecodesCharset(t *testing.T) {
+	tests := []struct {
+		name        string
+		fixture     string
+		contentType string
+	}{
+		{"GBK 响应头", "thread_gbk.html", "text/html; charset=gbk"},
+		{"GBK meta", "thread_gbk_meta.html", "text/html"},
+		{"UTF-8 响应头", "thread_utf8.html", "text/html; charset=utf-8"},
+		{"UTF-8 meta", "thread_utf8_meta.html", "text/html"},
+		// 前 1024 字节都是 ASCII 且没有声明编码，不能按 windows-1252 转码
+		{"UTF-8 未声明编码", "thread_utf8_longhead.html", "text/html"},
+	}
+
+	wantRows := map[string]string{
+		"价格:":   "12999 元",
+		"所在地:":  "上海 浦东新区",
+		"交易范围:": "同城面交或顺丰到付",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write(body)
+			}))
+			defer server.Close()
+
+			content, err := NewClient().GetHTML(context.Background(), server.URL, "", nil)
+			if err != nil {
+				t.Fatalf("GetHTML: %v", err)
+			}
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := doc.Find("title").Text(), "出 RTX 4090 公版显卡 - 二手交易 - Chiphell"; got != want {
+				t.Errorf("title = %q, want %q", got, want)
+			}
+			if got, want := doc.Find("#thread_subject").Text(), "出 RTX 4090 公版显卡，镕铭散热"; got != want {
+				t.Errorf("#thread_subject = %q, want %q", got, want)
+			}
+
+			rows := doc.Find(".typeoption tbody tr")
+			if rows.Length() != len(wantRows) {
+				t.Fatalf(".typeoption 行数 = %d, want %d", rows.Length(), len(wantRows))
+			}
+			rows.Each(func(i int, tr *goquery.Selection) {
+				th := strings.TrimSpace(tr.Find("th").Text())
+				td := strings.TrimSpace(tr.Find("td").Text())
+				if want, ok := wantRows[th]; !ok || td != want {
+					t.Errorf(".typeoption %q = %q, want %q", th, td, want)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package fetch
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	customproxy "golang.org/x/net/proxy"
+)
+
+const (
+	DefaultTimeout   = 10 * time.Second
+	DefaultUserAgent = "Mozilla/5.0"
+)
+
+// Client 所有子系统共享的 HTTP 客户端。按代理缓存 Transport 以复用连接，
+// 统一超时、默认请求头和 cookie jar
+type Client struct {
+	timeout time.Duration
+	headers map[string]string
+	jar     http.CookieJar
+	// insecureProxyTLS 经由代理的请求跳过 TLS 证书校验，直连始终校验
+	insecureProxyTLS bool
+
+	mu         sync.Mutex
+	direct     *http.Transport
+	transports map[string]*http.Transport // key 为代理地址
+}
+
+// Option 客户端配置项
+type Option func(*Client)
+
+// WithTimeout 设置单次请求的超时时间
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithHeaders 设置每个请求默认携带的请求头，单次请求传入的同名请求头优先
+func WithHeaders(headers map[string]string) Option {
+	return func(c *Client) {
+		for key, value := range headers {
+			c.headers[key] = value
+		}
+	}
+}
+
+// WithCookieJar 设置共享的 cookie jar
+func WithCookieJar(jar http.CookieJar) Option {
+	return func(c *Client) {
+		c.jar = jar
+	}
+}
+
+// WithInsecureProxyTLS 经由代理的请求跳过 TLS 证书校验。部分代理会替换证书，
+// 开启后这类代理可以读取请求内容（包括 cookies），仅在信任代理时使用
+func WithInsecureProxyTLS(insecure bool) Option {
+	return func(c *Client) {
+		c.insecureProxyTLS = insecure
+	}
+}
+
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		timeout:    DefaultTimeout,
+		headers:    map[string]string{"User-Agent": DefaultUserAgent},
+		transports: make(map[string]*http.Transport),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.direct = newTransport(false)
+	return c
+}
+
+// defaultClient 包级函数及未注入客户端的调用方使用的默认客户端，不带 cookie jar
+var defaultClient = NewClient()
+
+// Default 返回默认客户端
+func Default() *Client {
+	return defaultClient
+}
+
+// newTransport 创建 Transport，insecure 为 true 时跳过 TLS 证书校验
+func newTransport(insecure bool) *http.Transport {
+	return &http.Transport{
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: insecure},
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// transport 返回代理对应的 Transport，proxyAddr 为空时直连
+func (c *Client) transport(proxyAddr string) (*http.Transport, error) {
+	proxyAddr = strings.TrimSpace(proxyAddr)
+	if proxyAddr == "" {
+		return c.direct, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if transport, ok := c.transports[proxyAddr]; ok {
+		return transport, nil
+	}
+
+	proxyURL, err := ParseProxyURL(proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("解析代理 URL 失败: %v", err)
+	}
+
+	transport := newTransport(c.insecureProxyTLS)
+	switch proxyURL.Scheme {
+	case "http", "https":
+		// 代理地址带 user:pass 时，Transport 会自动附加 Proxy-Authorization（Basic）
+		transport.Proxy = http.ProxyURL(proxyURL)
+	case "socks5", "socks5h":
+		dialContext, err := socksDialContext(proxyURL)
+		if err != nil {
+			return nil, err
+		}
+		transport.DialContext = dialContext
+	}
+
+	c.transports[proxyAddr] = transport
+	return transport, nil
+}
+
+// socksDialContext 创建经由 SOCKS5 代理的拨号函数。socks5 在本地解析目标域名后再交给代理，
+// socks5h 直接把域名交给代理服务器解析
+func socksDialContext(proxyURL *url.URL) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	var auth *customproxy.Auth
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		auth = &customproxy.Auth{User: proxyURL.User.Username(), Password: password}
+	}
+
+	dialer, err := customproxy.SOCKS5("tcp", proxyURL.Host, auth, customproxy.Direct)
+	if err != nil {
+		return nil, fmt.Errorf("创建 SOCKS 代理失败: %v", err)
+	}
+	contextDialer, ok := dialer.(customproxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("SOCKS 代理不支持 context")
+	}
+
+	if proxyURL.Scheme == "socks5h" {
+		return contextDialer.DialContext, nil
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if net.ParseIP(host) == nil {
+			ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			if len(ips) == 0 {
+				return nil, fmt.Errorf("无法解析域名: %s", host)
+			}
+			addr = net.JoinHostPort(ips[0].IP.String(), port)
+		}
+		return contextDialer.DialContext(ctx, network, addr)
+	}, nil
+}
+
+// Forget 关闭并移除代理对应的 Transport，代理被移出代理池时调用
+func (c *Client) Forget(proxyAddr string) {
+	c.mu.Lock()
+	transport, ok := c.transports[proxyAddr]
+	delete(c.transports, proxyAddr)
+	c.mu.Unlock()
+
+	if ok {
+		transport.CloseIdleConnections()
+	}
+}
+
+// HTTPClient 返回通过指定代理发送请求的 http.Client，proxyAddr 为空时直连
+func (c *Client) HTTPClient(proxyAddr string) (*http.Client, error) {
+	transport, err := c.transport(proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.timeout,
+		Jar:       c.jar,
+	}, nil
+}
+
+// Do 补充默认请求头后发送请求
+func (c *Client) Do(req *http.Request, proxyAddr string) (*http.Response, error) {
+	client, err := c.HTTPClient(proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range c.headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	}
+	return client.Do(req)
+}
+
+// Get 发送 GET 请求，headers 会覆盖同名的默认请求头
+func (c *Client) Get(ctx context.Context, targetURL, proxyAddr string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", targetURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.Do(req, proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	return resp, nil
+}
+
+// ErrBlocked 页面显示访问受限、频率限制或验证码，通常是当前 IP 被目标网站限制。
+// 页面校验返回包装了该错误的结果时，对应代理计为请求失败
+var ErrBlocked = errors.New("访问受限")
+
+// StatusError 响应状态码不是 200
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("无效的响应状态码: %d", e.Code)
+}
+
+// GetHTML 发送 GET 请求并返回页面 HTML，响应状态码不是 200 时返回错误
+func (c *Client) GetHTML(ctx context.Context, targetURL, proxyAddr string, headers map[string]string) (string, error) {
+	resp, err := c.Get(ctx, targetURL, proxyAddr, headers)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// 读取剩余内容以便复用连接
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return "", &StatusError{Code: resp.StatusCode}
+	}
+
+	doc, err := goquery.NewDocumentFromReader(DecodeBody(resp))
+	if err != nil {
+		return "", fmt.Errorf("解析 HTML 失败: %v", err)
+	}
+
+	html, _ := doc.Html()
+	return html, nil
+}
@@ -4,136 +4,74 @@ package fetch
 
 import (
 	"context"
-	"crypto/tls"
+	"errors"
 	"fmt"
-	"net"
-	"net/http"
 	"net/url"
-	"strings"
 	"time"
 
 	mylog "github.com/langchou/informer/pkg/log"
 	"github.com/langchou/informer/pkg/proxy"
-	customproxy "golang.org/x/net/proxy"
-
-	"github.com/PuerkitoBio/goquery"
 )
 
+// FetchWithProxies 使用默认客户端和默认代理池请求页面
 func FetchWithProxies(targetURL string, headers map[string]string) (string, error) {
-	// 检查代理池数量
-	proxyCount, err := proxy.GetProxyCount()
-	if err != nil {
-		return "", fmt.Errorf("获取代理数量失败: %v", err)
-	}
+	return defaultClient.FetchWithProxies(context.Background(), proxy.Default(), targetURL, headers, nil)
+}
 
-	// 如果代理池为空，等待一段时间
-	if proxyCount == 0 {
-		mylog.Warn("代理池为空，等待30秒后重试")
-		time.Sleep(30 * time.Second)
+// FetchWithProxies 从代理池中选择代理请求页面，失败时换用其他代理重试。
+// validate 不为空时校验页面内容：返回 ErrBlocked 的代理计为失败并换用其他代理，其他校验错误直接返回
+func (c *Client) FetchWithProxies(ctx context.Context, pool *proxy.Pool, targetURL string, headers map[string]string, validate func(content string) error) (string, error) {
+	// 代理池为空时直接返回，由调用方决定何时重试
+	if pool.Count() == 0 {
 		return "", fmt.Errorf("代理池为空，请稍后重试")
 	}
 
-	// 首先尝试使用优选代理
-	preferredCount := proxy.GetPreferredProxyCount()
-	if preferredCount > 0 {
-		proxyIP, err := proxy.GetProxy()
-		if err == nil {
-			content, err := FetchWithProxy(proxyIP, targetURL, headers)
-			if err == nil {
-				mylog.Debug(fmt.Sprintf("使用优选代理 %s 请求成功", proxyIP))
-				return content, nil
-			} else {
-				mylog.Warn(fmt.Sprintf("使用优选代理 %s 请求失败: %v", proxyIP, err))
-				proxy.RemoveProxy(proxyIP)
-			}
+	// 按评分选择代理，失败的代理进入冷却期，下一次会换用其他代理
+	maxRetries := 4
+	var lastErr error
+	for i := 0; i < maxRetries; i++ {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
 		}
-	}
 
-	// 如果优选代理都失败了，使用普通代理
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		proxyIP, err := proxy.GetProxy()
+		proxyIP, err := pool.Get()
 		if err != nil {
+			if lastErr != nil {
+				return "", fmt.Errorf("所有重试都失败: %w", lastErr)
+			}
 			return "", fmt.Errorf("获取代理失败: %v", err)
 		}
 
-		content, err := FetchWithProxy(proxyIP, targetURL, headers)
+		begin := time.Now()
+		content, err := c.GetHTML(ctx, targetURL, proxyIP, headers)
+		if err == nil && validate != nil {
+			// 页面不是访问受限时，校验失败与代理无关
+			if err = validate(content); err != nil && !errors.Is(err, ErrBlocked) {
+				return "", err
+			}
+		}
 		if err == nil {
+			pool.ReportSuccess(proxyIP, time.Since(begin))
+			mylog.Debug(fmt.Sprintf("使用代理 %s 请求成功", proxy.Redact(proxyIP)))
 			return content, nil
-		} else {
-			mylog.Warn(fmt.Sprintf("使用代理 %s 请求失败: %v", proxyIP, err))
-			proxy.RemoveProxy(proxyIP)
 		}
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		mylog.Warn(fmt.Sprintf("使用代理 %s 请求失败: %v", proxy.Redact(proxyIP), err))
+		pool.ReportFailure(proxyIP)
+		lastErr = err
 	}
 
-	return "", fmt.Errorf("所有重试都失败")
+	return "", fmt.Errorf("所有重试都失败: %w", lastErr)
 }
 
+// FetchWithProxy 通过指定代理请求页面
 func FetchWithProxy(proxyIP string, targetURL string, headers map[string]string) (string, error) {
-	// 清理代理IP字符串
-	proxyIP = strings.TrimSpace(proxyIP)
-
-	// 解析代理URL
-	proxyURL, err := ParseProxyURL(proxyIP)
-	if err != nil {
-		return "", fmt.Errorf("解析代理 URL 失败: %v", err)
-	}
-
-	// 创建 SOCKS5 拨号器
-	dialer, err := customproxy.SOCKS5("tcp", proxyURL.Host, nil, customproxy.Direct)
-	if err != nil {
-		return "", fmt.Errorf("创建 SOCKS 代理失败: %v", err)
-	}
-
-	transport := &http.Transport{
-		// 移除 Proxy 字段，因为我们使用 SOCKS5 拨号器
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return dialer.Dial(network, addr)
-		},
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   5 * time.Second,
-	}
-
-	req, err := http.NewRequest("GET", targetURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("创建请求失败: %v", err)
-	}
-
-	for key, value := range headers {
-		req.Header.Set(key, value)
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("请求失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("无效的响应状态码: %d", resp.StatusCode)
-	}
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("解析 HTML 失败: %v", err)
-	}
-
-	html, _ := doc.Html()
-	return html, nil
+	return defaultClient.GetHTML(context.Background(), targetURL, proxyIP, headers)
 }
 
+// ParseProxyURL 解析代理地址，支持 http、https、socks5、socks5h，未写明协议时按 socks5 处理
 func ParseProxyURL(proxyIP string) (*url.URL, error) {
-	// 清理代理IP字符串中的空白字符
-	proxyIP = strings.TrimSpace(proxyIP)
-
-	if strings.HasPrefix(proxyIP, "socks5://") {
-		// 如果已经是 socks5:// 开头，直接解析
-		return url.Parse(proxyIP)
-	}
-	// 如果不是 socks5:// 开头，添加前缀
-	return url.Parse("socks5://" + proxyIP)
+	return proxy.ParseURL(proxyIP)
 }
@@ -0,0 +1,107 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/proxy"
+)
+
+// raceResult 单个代理的请求结果
+type raceResult struct {
+	proxy   string
+	content string
+	err     error
+	invalid bool // 请求成功但内容未通过校验
+	latency time.Duration
+}
+
+// RaceWithProxies 同时通过代理池中的 n 个代理请求页面，返回第一个通过 validate 校验的结果并取消其余请求。
+// 成功的代理记录响应时间；请求失败或页面校验返回 ErrBlocked 的代理进入冷却期，
+// 其他校验错误不计为代理失败。validate 只应判断页面是否可用，登录状态等由调用方解析页面后处理
+func (c *Client) RaceWithProxies(ctx context.Context, pool *proxy.Pool, targetURL string, headers map[string]string, n int, validate func(content string) error) (string, error) {
+	proxies := pickProxies(pool, n)
+	if len(proxies) == 0 {
+		if pool.Count() == 0 {
+			return "", fmt.Errorf("代理池为空，请稍后重试")
+		}
+		return "", fmt.Errorf("获取代理失败: 代理池中的代理均在冷却中")
+	}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan raceResult, len(proxies))
+	for _, proxyIP := range proxies {
+		go func(proxyIP string) {
+			begin := time.Now()
+			content, err := c.GetHTML(raceCtx, targetURL, proxyIP, headers)
+			result := raceResult{proxy: proxyIP, content: content, err: err, latency: time.Since(begin)}
+			if err == nil && validate != nil {
+				if err := validate(content); err != nil {
+					result.err, result.invalid = err, true
+				}
+			}
+			results <- result
+		}(proxyIP)
+	}
+
+	var winner *raceResult
+	var requestErr, validateErr error
+	for range proxies {
+		result := <-results
+		switch {
+		case result.err == nil:
+			pool.ReportSuccess(result.proxy, result.latency)
+			if winner == nil {
+				winner = &result
+				// 取消其余仍在进行的请求
+				cancel()
+			}
+		case result.invalid:
+			if errors.Is(result.err, ErrBlocked) {
+				pool.ReportFailure(result.proxy)
+			}
+			validateErr = result.err
+		case errors.Is(raceCtx.Err(), context.Canceled) && winner != nil:
+			// 已有代理胜出后被取消的请求，不计入评分
+		default:
+			if ctx.Err() == nil {
+				pool.ReportFailure(result.proxy)
+			}
+			requestErr = result.err
+		}
+	}
+
+	if winner != nil {
+		mylog.Debug(fmt.Sprintf("并发请求 %d 个代理，%s 最先返回，耗时 %s", len(proxies), proxy.Redact(winner.proxy), winner.latency.Round(time.Millisecond)))
+		return winner.content, nil
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if validateErr != nil {
+		return "", fmt.Errorf("所有代理均未返回有效页面: %w", validateErr)
+	}
+	return "", fmt.Errorf("所有代理均请求失败: %v", requestErr)
+}
+
+// pickProxies 从代理池中选择至多 n 个不同的代理
+func pickProxies(pool *proxy.Pool, n int) []string {
+	seen := make(map[string]bool)
+	var proxies []string
+	for attempts := 0; len(proxies) < n && attempts < n*3; attempts++ {
+		proxyIP, err := pool.Get()
+		if err != nil {
+			break
+		}
+		if !seen[proxyIP] {
+			seen[proxyIP] = true
+			proxies = append(proxies, proxyIP)
+		}
+	}
+	return proxies
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "informer"
+
+var (
+	// FetchAttempts 列表页抓取次数
+	FetchAttempts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_attempts_total",
+		Help:      "列表页抓取次数",
+	})
+
+	// FetchFailures 列表页抓取失败次数，按原因区分
+	FetchFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "fetch_failures_total",
+		Help:      "列表页抓取失败次数",
+	}, []string{"reason"})
+
+	// FetchDuration 列表页抓取耗时
+	FetchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fetch_duration_seconds",
+		Help:      "列表页抓取耗时（秒）",
+		Buckets:   []float64{0.25, 0.5, 1, 2, 5, 10, 30, 60},
+	})
+
+	// PostsParsed 每轮解析出的帖子数
+	PostsParsed = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "posts_parsed_per_cycle",
+		Help:      "每轮从列表页解析出的帖子数",
+		Buckets:   []float64{0, 1, 5, 10, 20, 30, 50, 100},
+	})
+
+	// NewPosts 新帖子数
+	NewPosts = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "new_posts_total",
+		Help:      "检测到的新帖子数",
+	})
+
+	// KeywordMatches 关键词命中次数，按用户区分，user 标签为配置的别名或 user1、user2 这样的序号
+	KeywordMatches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "keyword_matches_total",
+		Help:      "关键词命中次数",
+	}, []string{"user"})
+
+	// Notifications 通知发送次数，按渠道和结果区分
+	Notifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "通知发送次数",
+	}, []string{"channel", "result"})
+
+	// ProxyPoolSize 代理池中的代理数
+	ProxyPoolSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "proxy_pool_size",
+		Help:      "代理池中的代理数",
+	})
+
+	// PreferredProxies 优选代理数
+	PreferredProxies = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "proxy_preferred_count",
+		Help:      "优选代理数",
+	})
+
+	// ProxyChecks 代理检测次数，按结果区分
+	ProxyChecks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proxy_checks_total",
+		Help:      "代理检测次数",
+	}, []string{"result"})
+)
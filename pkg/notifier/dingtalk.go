@@ -12,6 +12,7 @@ import (
 	"time"
 
 	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/metrics"
 )
 
 type DingTalkNotifier struct {
@@ -26,6 +27,15 @@ func NewDingTalkNotifier(token, secret string) *DingTalkNotifier {
 	}
 }
 
+// recordResult 记录通知发送结果指标
+func recordResult(err error) {
+	if err != nil {
+		metrics.Notifications.WithLabelValues("dingtalk", "failed").Inc()
+		return
+	}
+	metrics.Notifications.WithLabelValues("dingtalk", "sent").Inc()
+}
+
 func (n *DingTalkNotifier) sign(timestamp int64) string {
 	stringToSign := fmt.Sprintf("%d\n%s", timestamp, n.secret)
 	h := hmac.New(sha256.New, []byte(n.secret))
@@ -33,7 +43,9 @@ func (n *DingTalkNotifier) sign(timestamp int64) string {
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }
 
-func (n *DingTalkNotifier) SendNotification(title, message string, atMobiles []string) error {
+func (n *DingTalkNotifier) SendNotification(title, message string, atMobiles []string) (err error) {
+	defer func() { recordResult(err) }()
+
 	timestamp := time.Now().UnixMilli()
 	sign := n.sign(timestamp)
 
@@ -114,7 +126,9 @@ func (n *DingTalkNotifier) ReportError(title, message string) error {
 }
 
 // SendTextNotification 发送text类型消息，更好地支持@功能
-func (n *DingTalkNotifier) SendTextNotification(title, message string, atMobiles []string) error {
+func (n *DingTalkNotifier) SendTextNotification(title, message string, atMobiles []string) (err error) {
+	defer func() { recordResult(err) }()
+
 	timestamp := time.Now().UnixMilli()
 	sign := n.sign(timestamp)
 
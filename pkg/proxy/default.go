@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"context"
+)
+
+// defaultPool 包级函数使用的默认代理池，保持与原有调用方式兼容
+var defaultPool = NewPool()
+
+// Default 返回默认代理池
+func Default() *Pool {
+	return defaultPool
+}
+
+// SetProxyAPI 设置默认代理池的代理API URL
+func SetProxyAPI(url string) {
+	defaultPool.mu.Lock()
+	defaultPool.api = url
+	defaultPool.mu.Unlock()
+}
+
+// UpdateProxyPool 更新默认代理池
+func UpdateProxyPool() error {
+	return defaultPool.Update(context.Background())
+}
+
+// StartProxyPoolManager 启动默认代理池的管理器
+func StartProxyPoolManager(ctx context.Context) {
+	defaultPool.StartManager(ctx)
+}
+
+// GetProxy 从默认代理池中选择一个代理
+func GetProxy() (string, error) {
+	return defaultPool.Get()
+}
+
+// GetProxyCount 获取默认代理池中的代理数量
+func GetProxyCount() (int64, error) {
+	return int64(defaultPool.Count()), nil
+}
+
+// RemoveProxy 从默认代理池中删除指定代理
+func RemoveProxy(proxy string) {
+	defaultPool.Remove(proxy)
+}
+
+// GetPreferredProxyCount 获取默认代理池中的优选代理数量
+func GetPreferredProxyCount() int {
+	return defaultPool.PreferredCount()
+}
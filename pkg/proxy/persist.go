@@ -0,0 +1,127 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+// savedProxy 保存到状态文件中的代理及其评分
+type savedProxy struct {
+	Address             string    `json:"address"`
+	FirstSeen           time.Time `json:"firstSeen"`
+	LastSeen            time.Time `json:"lastSeen"`
+	Preferred           bool      `json:"preferred"`
+	Successes           int       `json:"successes"`
+	Failures            int       `json:"failures"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	LatencyMs           float64   `json:"latencyMs"`
+	CooldownUntil       time.Time `json:"cooldownUntil"`
+}
+
+// LoadState 从状态文件恢复代理池及评分，文件不存在时忽略。
+// 应在第一次 Update 之前调用，已超过 TTL 的代理会在之后的刷新中过期
+func (p *Pool) LoadState(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取代理池状态文件失败: %v", err)
+	}
+
+	var saved []savedProxy
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("解析代理池状态文件失败: %v", err)
+	}
+
+	p.mu.Lock()
+	for _, s := range saved {
+		if _, ok := p.proxies[s.Address]; ok || s.Address == "" {
+			continue
+		}
+		p.proxies[s.Address] = &proxyEntry{
+			firstSeen:           s.FirstSeen,
+			lastSeen:            s.LastSeen,
+			preferred:           s.Preferred,
+			successes:           s.Successes,
+			failures:            s.Failures,
+			consecutiveFailures: s.ConsecutiveFailures,
+			latency:             s.LatencyMs,
+			cooldownUntil:       s.CooldownUntil,
+		}
+	}
+	p.mu.Unlock()
+
+	p.updateMetrics()
+	mylog.Info(fmt.Sprintf("已从 %s 恢复 %d 个代理，其中优选代理 %d 个", path, len(saved), p.PreferredCount()))
+	return nil
+}
+
+// SaveState 将代理池及评分写入状态文件
+func (p *Pool) SaveState(path string) error {
+	p.mu.RLock()
+	saved := make([]savedProxy, 0, len(p.proxies))
+	for address, entry := range p.proxies {
+		saved = append(saved, savedProxy{
+			Address:             address,
+			FirstSeen:           entry.firstSeen,
+			LastSeen:            entry.lastSeen,
+			Preferred:           entry.preferred,
+			Successes:           entry.successes,
+			Failures:            entry.failures,
+			ConsecutiveFailures: entry.consecutiveFailures,
+			LatencyMs:           entry.latency,
+			CooldownUntil:       entry.cooldownUntil,
+		})
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(saved, func(i, j int) bool {
+		return saved[i].Address < saved[j].Address
+	})
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化代理池失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("创建代理池状态目录失败: %v", err)
+	}
+
+	// 先写临时文件再重命名，避免写入中途退出导致文件损坏；代理地址可能带有密码，仅允许所有者读写
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("保存代理池失败: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("保存代理池失败: %v", err)
+	}
+	return nil
+}
+
+// StartAutoSave 定期保存代理池，ctx 取消时保存最后一次后返回
+func (p *Pool) StartAutoSave(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := p.SaveState(path); err != nil {
+				mylog.Error(err.Error())
+			}
+			return
+		case <-ticker.C:
+			if err := p.SaveState(path); err != nil {
+				mylog.Error(err.Error())
+			}
+		}
+	}
+}
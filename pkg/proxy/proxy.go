@@ -3,232 +3,379 @@ package proxy
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
+	"math/rand"
 	"net/http"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/langchou/informer/pkg/checker"
 	mylog "github.com/langchou/informer/pkg/log"
+	"github.com/langchou/informer/pkg/metrics"
 )
 
 const (
-	UpdateInterval = 5 * time.Minute // 代理池更新间隔
-	CheckInterval  = 5 * time.Minute // 改为10分钟检测一次
-)
+	UpdateInterval = 5 * time.Minute  // 代理池更新间隔
+	WatchInterval  = 10 * time.Second // 检查代理文件变化的间隔
 
-var ProxyAPI string
+	DefaultTTL      = 30 * time.Minute // 代理来源连续多久未返回某代理后将其移出代理池
+	DefaultMinRatio = 0.2              // 来源返回的代理数少于现有数量的该比例时视为异常
+)
 
-// ProxyPool 代理池结构
-type ProxyPool struct {
-	sync.RWMutex
-	proxies         map[string]bool      // 普通代理池
-	preferredProxies map[string]float64  // 优选代理池，值为响应时间
+// Pool 代理池，检测通过的代理标记为优选代理。不同的 Pool 可以使用不同的代理来源
+type Pool struct {
+	mu      sync.RWMutex
+	proxies map[string]*proxyEntry
+
+	api         string // 按行分隔的文本代理接口
+	sources     []ProxySource
+	ttl         time.Duration
+	minRatio    float64
+	httpClient  *http.Client // 请求 api 使用的客户端
+	removeHooks []func(proxy string)
 }
 
-var proxyPool = &ProxyPool{
-	proxies:         make(map[string]bool),
-	preferredProxies: make(map[string]float64),
+// Option 代理池配置项
+type Option func(*Pool)
+
+// WithAPI 使用按行分隔的文本代理接口作为代理来源
+func WithAPI(url string) Option {
+	return func(p *Pool) {
+		p.api = url
+	}
 }
 
-// SetProxyAPI 设置代理API URL
-func SetProxyAPI(url string) {
-	ProxyAPI = url
+// WithSources 添加代理来源，与 WithAPI 同时使用时合并
+func WithSources(sources ...ProxySource) Option {
+	return func(p *Pool) {
+		p.sources = append(p.sources, sources...)
+	}
 }
 
-// StartIPChecker 启动IP检测器
-func StartIPChecker(ctx context.Context) {
-	ticker := time.NewTicker(CheckInterval)
-	defer ticker.Stop()
+// WithTTL 设置代理来源连续多久未返回某代理后将其移出代理池
+func WithTTL(ttl time.Duration) Option {
+	return func(p *Pool) {
+		if ttl > 0 {
+			p.ttl = ttl
+		}
+	}
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			checkAllProxies()
+// WithMinRatio 设置异常结果判定比例，来源返回的代理数少于现有数量的该比例时保留现有代理池
+func WithMinRatio(ratio float64) Option {
+	return func(p *Pool) {
+		if ratio > 0 {
+			p.minRatio = ratio
 		}
 	}
 }
 
-// checkAllProxies 检查所有代理的可用性
-func checkAllProxies() {
-	proxyPool.RLock()
-	preferredCount := len(proxyPool.preferredProxies)
-	proxyPool.RUnlock()
+// WithHTTPClient 设置请求代理接口使用的客户端
+func WithHTTPClient(client *http.Client) Option {
+	return func(p *Pool) {
+		p.httpClient = client
+	}
+}
 
-	if preferredCount > 50 {
-		mylog.Info(fmt.Sprintf("当前优选代理数量充足: %d，跳过检测", preferredCount))
-		return
+// WithRemoveHook 注册代理移出代理池时的回调，例如释放为该代理缓存的连接
+func WithRemoveHook(hook func(proxy string)) Option {
+	return func(p *Pool) {
+		p.removeHooks = append(p.removeHooks, hook)
 	}
+}
 
-	proxyPool.RLock()
-	proxies := make([]string, 0, len(proxyPool.proxies))
-	for proxy := range proxyPool.proxies {
-		proxies = append(proxies, proxy)
+func NewPool(opts ...Option) *Pool {
+	p := &Pool{
+		proxies:  make(map[string]*proxyEntry),
+		ttl:      DefaultTTL,
+		minRatio: DefaultMinRatio,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
-	proxyPool.RUnlock()
+	return p
+}
 
-	mylog.Info(fmt.Sprintf("开始检测代理池中的IP，共 %d 个代理", len(proxies)))
+// currentSources 返回所有代理来源，api 作为按行分隔的文本接口处理
+func (p *Pool) currentSources() MultiSource {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
-	checkedCount := 0
-	for _, proxyIP := range proxies {
-		valid, responseTime := checker.CheckIP(proxyIP)
-		if valid {
-			proxyPool.Lock()
-			proxyPool.preferredProxies[proxyIP] = responseTime
-			proxyPool.Unlock()
-			mylog.Debug(fmt.Sprintf("添加新的优选代理: %s, 响应时间: %.2fms", proxyIP, responseTime))
-			checkedCount++
-		}
+	all := append(MultiSource{}, p.sources...)
+	if p.api != "" {
+		all = append(all, &APISource{URL: p.api, Client: p.httpClient})
+	}
+	return all
+}
 
-		if checkedCount >= 10 {
-			mylog.Info("已找到足够的优选代理，停止检测")
-			break
+// Enabled 是否配置了代理来源
+func (p *Pool) Enabled() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.api != "" || len(p.sources) > 0
+}
+
+func (p *Pool) notifyRemoved(proxies []string) {
+	p.mu.RLock()
+	hooks := p.removeHooks
+	p.mu.RUnlock()
+
+	for _, proxy := range proxies {
+		for _, hook := range hooks {
+			hook(proxy)
 		}
 	}
-
-	proxyPool.RLock()
-	count := len(proxyPool.preferredProxies)
-	proxyPool.RUnlock()
-	mylog.Info(fmt.Sprintf("IP检测完成，当前优选代理数量: %d", count))
 }
 
-// UpdateProxyPool 更新代理池
-func UpdateProxyPool() error {
-	if ProxyAPI == "" {
-		return fmt.Errorf("ProxyAPI URL not set")
+// Update 从代理来源获取代理并合并到代理池：新代理加入，已有代理刷新最近出现时间，
+// 超过 TTL 未出现的代理移出代理池。来源返回错误或代理数量异常少时保留现有代理池
+func (p *Pool) Update(ctx context.Context) error {
+	source := p.currentSources()
+	if len(source) == 0 {
+		return fmt.Errorf("未配置代理来源")
 	}
 
-	newProxies, err := fetchNewProxies()
+	newProxies, err := source.Fetch(ctx)
 	if err != nil {
-		return fmt.Errorf("获取新代理失败: %v", err)
+		return fmt.Errorf("获取新代理失败，保留现有代理池: %v", err)
 	}
 
-	proxyPool.Lock()
-	// 清空现有代理
-	proxyPool.proxies = make(map[string]bool)
-	// 添加新代理
+	p.mu.Lock()
+	now := time.Now()
+	// 只与 TTL 内出现过的代理比较：来源长期缩减或代理池来自过期的状态文件时，
+	// 旧代理过期后新的结果不会一直被拒绝
+	current := 0
+	for _, entry := range p.proxies {
+		if now.Sub(entry.lastSeen) <= p.ttl {
+			current++
+		}
+	}
+	if current > 0 && float64(len(newProxies)) < float64(current)*p.minRatio {
+		p.mu.Unlock()
+		return fmt.Errorf("代理来源仅返回 %d 个代理（现有 %d 个），疑似异常，保留现有代理池", len(newProxies), current)
+	}
+
+	added := 0
 	for _, proxy := range newProxies {
-		proxyPool.proxies[proxy] = true
+		if entry, ok := p.proxies[proxy]; ok {
+			entry.lastSeen = now
+			continue
+		}
+		p.proxies[proxy] = &proxyEntry{firstSeen: now, lastSeen: now}
+		added++
 	}
-	proxyPool.Unlock()
 
-	count := len(newProxies)
-	mylog.Info(fmt.Sprintf("代理池更新完成，当前代理数量: %d", count))
+	var expired []string
+	for proxy, entry := range p.proxies {
+		if now.Sub(entry.lastSeen) > p.ttl {
+			delete(p.proxies, proxy)
+			expired = append(expired, proxy)
+		}
+	}
+	count := len(p.proxies)
+	p.mu.Unlock()
+	p.notifyRemoved(expired)
+
+	p.updateMetrics()
+	mylog.Info(fmt.Sprintf("代理池更新完成，新增 %d 个，过期移除 %d 个，当前代理数量: %d", added, len(expired), count))
 	return nil
 }
 
-// 获取新代理的辅助函数
-func fetchNewProxies() ([]string, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// Get 按评分加权随机选择一个不在冷却期的代理，优选代理、成功率高、响应快的代理被选中的概率更高
+func (p *Pool) Get() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.proxies) == 0 {
+		return "", fmt.Errorf("代理池为空")
 	}
 
-	req, err := http.NewRequest("GET", ProxyAPI, nil)
-	if err != nil {
-		return nil, fmt.Errorf("创建请求失败: %v", err)
+	now := time.Now()
+	var total float64
+	candidates := make([]string, 0, len(p.proxies))
+	weights := make([]float64, 0, len(p.proxies))
+	for proxy, entry := range p.proxies {
+		if entry.coolingDown(now) {
+			continue
+		}
+		weight := entry.weight(now)
+		candidates = append(candidates, proxy)
+		weights = append(weights, weight)
+		total += weight
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("代理池中的代理均在冷却中")
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("请求失败: %v", err)
+	selected := candidates[len(candidates)-1]
+	r := rand.Float64() * total
+	for i, weight := range weights {
+		r -= weight
+		if r < 0 {
+			selected = candidates[i]
+			break
+		}
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+	p.proxies[selected].lastUsed = now
+	return selected, nil
+}
+
+// ReportSuccess 记录代理请求成功及响应时间
+func (p *Pool) ReportSuccess(proxy string, latency time.Duration) {
+	p.mu.Lock()
+	if entry, ok := p.proxies[proxy]; ok {
+		entry.recordSuccess(float64(latency.Milliseconds()))
 	}
+	p.mu.Unlock()
+}
 
-	proxies := strings.Split(strings.TrimSpace(string(body)), "\n")
-	var cleanProxies []string
-	for _, p := range proxies {
-		proxy := strings.TrimSpace(p)
-		if proxy != "" {
-			if !strings.HasPrefix(proxy, "socks5://") {
-				proxy = "socks5://" + proxy
-			}
-			cleanProxies = append(cleanProxies, proxy)
-		}
+// ReportFailure 记录代理请求失败，代理进入冷却期而不是直接移出代理池
+func (p *Pool) ReportFailure(proxy string) {
+	p.mu.Lock()
+	if entry, ok := p.proxies[proxy]; ok {
+		entry.recordFailure(time.Now())
 	}
+	p.mu.Unlock()
+	p.updateMetrics()
+}
 
-	return cleanProxies, nil
+// Count 获取当前代理池中的代理数量
+func (p *Pool) Count() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.proxies)
 }
 
-// GetProxy 获取一个代理
-func GetProxy() (string, error) {
-	// 首先尝试从优选代理池中获取
-	proxyPool.RLock()
-	if len(proxyPool.preferredProxies) > 0 {
-		// 获取响应时间最短的代理
-		var bestProxy string
-		var bestTime float64 = float64(^uint64(0) >> 1) // 最大float64值
-		for proxy, time := range proxyPool.preferredProxies {
-			if time < bestTime {
-				bestProxy = proxy
-				bestTime = time
-			}
-		}
-		proxyPool.RUnlock()
-		return bestProxy, nil
+// Proxies 返回代理池中的所有代理
+func (p *Pool) Proxies() []string {
+	p.mu.RLock()
+	proxies := make([]string, 0, len(p.proxies))
+	for proxy := range p.proxies {
+		proxies = append(proxies, proxy)
 	}
-	proxyPool.RUnlock()
+	p.mu.RUnlock()
+	return proxies
+}
 
-	// 如果没有优选代理，从普通代理池中随机获取一个
-	proxyPool.RLock()
-	defer proxyPool.RUnlock()
-	
-	if len(proxyPool.proxies) == 0 {
-		return "", fmt.Errorf("代理池为空")
+// SetPreferred 将检测通过的代理标记为优选代理，latency 为响应时间（毫秒）
+func (p *Pool) SetPreferred(proxy string, latency float64) {
+	p.mu.Lock()
+	if entry, ok := p.proxies[proxy]; ok {
+		entry.preferred = true
+		entry.recordSuccess(latency)
 	}
+	p.mu.Unlock()
+	p.updateMetrics()
+}
 
-	// 随机选择一个代理
-	for proxy := range proxyPool.proxies {
-		return proxy, nil // 返回第一个找到的代理
+// Demote 检测未通过的代理取消优选并进入冷却期
+func (p *Pool) Demote(proxy string) {
+	p.mu.Lock()
+	if entry, ok := p.proxies[proxy]; ok {
+		entry.preferred = false
+		entry.recordFailure(time.Now())
 	}
+	p.mu.Unlock()
+	p.updateMetrics()
+}
 
-	return "", fmt.Errorf("无法获取代理")
+// Remove 从代理池中删除指定代理
+func (p *Pool) Remove(proxy string) {
+	p.mu.Lock()
+	delete(p.proxies, proxy)
+	p.mu.Unlock()
+	p.notifyRemoved([]string{proxy})
+	p.updateMetrics()
 }
 
-// GetProxyCount 获取当前代理池中的代理数量
-func GetProxyCount() (int64, error) {
-	proxyPool.RLock()
-	count := len(proxyPool.proxies)
-	proxyPool.RUnlock()
-	return int64(count), nil
+// PreferredCount 获取优选代理数量
+func (p *Pool) PreferredCount() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	count := 0
+	for _, entry := range p.proxies {
+		if entry.preferred {
+			count++
+		}
+	}
+	return count
 }
 
-// RemoveProxy 从代理池中删除指定代理
-func RemoveProxy(proxy string) {
-	proxyPool.Lock()
-	delete(proxyPool.proxies, proxy)
-	delete(proxyPool.preferredProxies, proxy)
-	proxyPool.Unlock()
+// updateMetrics 更新代理池相关指标
+func (p *Pool) updateMetrics() {
+	metrics.ProxyPoolSize.Set(float64(p.Count()))
+	metrics.PreferredProxies.Set(float64(p.PreferredCount()))
 }
 
-// StartProxyPoolManager 启动代理池管理器
-func StartProxyPoolManager(ctx context.Context) {
+// StartManager 启动代理池管理器，定期更新代理池，代理文件变化时立即更新
+func (p *Pool) StartManager(ctx context.Context) {
 	ticker := time.NewTicker(UpdateInterval)
 	defer ticker.Stop()
+	watcher := time.NewTicker(WatchInterval)
+	defer watcher.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-watcher.C:
+			if !p.currentSources().Changed() {
+				continue
+			}
+			mylog.Info("代理文件已变化，重新加载代理池")
+			if err := p.Update(ctx); err != nil {
+				mylog.Error(fmt.Sprintf("更新代理池失败: %v", err))
+			}
 		case <-ticker.C:
-			if err := UpdateProxyPool(); err != nil {
+			if err := p.Update(ctx); err != nil {
 				mylog.Error(fmt.Sprintf("更新代理池失败: %v", err))
 			}
 		}
 	}
 }
 
-// GetPreferredProxyCount 获取优选代理数量
-func GetPreferredProxyCount() int {
-	proxyPool.RLock()
-	count := len(proxyPool.preferredProxies)
-	proxyPool.RUnlock()
-	return count
+// ProxyInfo 代理池中单个代理的信息
+type ProxyInfo struct {
+	Address       string    `json:"address"`
+	Preferred     bool      `json:"preferred"`
+	LatencyMs     float64   `json:"latencyMs,omitempty"`
+	Successes     int       `json:"successes"`
+	Failures      int       `json:"failures"`
+	Score         float64   `json:"score"`
+	CooldownUntil time.Time `json:"cooldownUntil,omitempty"`
+	FirstSeen     time.Time `json:"firstSeen,omitempty"`
+	LastSeen      time.Time `json:"lastSeen,omitempty"`
+}
+
+// Snapshot 返回代理池当前内容，按评分从高到低排列
+func (p *Pool) Snapshot() []ProxyInfo {
+	p.mu.RLock()
+	now := time.Now()
+	infos := make([]ProxyInfo, 0, len(p.proxies))
+	for proxy, entry := range p.proxies {
+		info := ProxyInfo{
+			Address:   Redact(proxy),
+			Preferred: entry.preferred,
+			LatencyMs: entry.latency,
+			Successes: entry.successes,
+			Failures:  entry.failures,
+			Score:     entry.score(),
+			FirstSeen: entry.firstSeen,
+			LastSeen:  entry.lastSeen,
+		}
+		if entry.coolingDown(now) {
+			info.CooldownUntil = entry.cooldownUntil
+		}
+		infos = append(infos, info)
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Score != infos[j].Score {
+			return infos[i].Score > infos[j].Score
+		}
+		return infos[i].Address < infos[j].Address
+	})
+	return infos
 }
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "proxy-test")
+	if err != nil {
+		panic(err)
+	}
+	mylog.InitLogger(filepath.Join(dir, "test.log"), 1, 1, 1, false, "error")
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func addresses(start, n int) []string {
+	list := make([]string, 0, n)
+	for i := start; i < start+n; i++ {
+		list = append(list, fmt.Sprintf("http://10.0.%d.%d:8080", i/256, i%256))
+	}
+	return list
+}
+
+// expire 将代理的最近出现时间移到 TTL 之前
+func expire(p *Pool, proxies ...string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, proxy := range proxies {
+		p.proxies[proxy].lastSeen = time.Now().Add(-p.ttl - time.Minute)
+	}
+}
+
+func TestUpdateMergesAndExpires(t *testing.T) {
+	source := &StaticSource{Proxies: addresses(0, 3)}
+	p := NewPool(WithSources(source), WithTTL(time.Hour))
+	if err := p.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	// 来源不再返回第一个代理，TTL 内仍保留
+	source.Proxies = addresses(1, 3)
+	if err := p.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := p.Count(); got != 4 {
+		t.Fatalf("合并后代理数量 = %d, want 4", got)
+	}
+
+	var removed []string
+	p.removeHooks = append(p.removeHooks, func(proxy string) { removed = append(removed, proxy) })
+	first := addresses(0, 1)[0]
+	expire(p, first)
+	if err := p.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got := p.Count(); got != 3 {
+		t.Fatalf("过期后代理数量 = %d, want 3", got)
+	}
+	if len(removed) != 1 || removed[0] != first {
+		t.Errorf("移除回调 = %v, want [%s]", removed, first)
+	}
+}
+
+func TestUpdateMinRatio(t *testing.T) {
+	source := &StaticSource{Proxies: addresses(0, 100)}
+	p := NewPool(WithSources(source), WithTTL(time.Hour))
+	if err := p.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	source.Proxies = addresses(100, 10)
+	if err := p.Update(context.Background()); err == nil {
+		t.Fatal("来源结果从 100 个降到 10 个时应保留现有代理池")
+	}
+	if got := p.Count(); got != 100 {
+		t.Fatalf("拒绝更新后代理数量 = %d, want 100", got)
+	}
+
+	// 旧代理超过 TTL 后不再参与比较，缩减后的结果被接受
+	expire(p, addresses(0, 100)...)
+	if err := p.Update(context.Background()); err != nil {
+		t.Fatalf("旧代理过期后 Update: %v", err)
+	}
+	if got := p.Count(); got != 10 {
+		t.Fatalf("更新后代理数量 = %d, want 10", got)
+	}
+}
+
+func TestGetSkipsCoolingDown(t *testing.T) {
+	p := NewPool(WithSources(&StaticSource{Proxies: addresses(0, 3)}))
+	if err := p.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	proxies := p.Proxies()
+	healthy := proxies[0]
+	for _, proxy := range proxies[1:] {
+		p.ReportFailure(proxy)
+	}
+
+	for i := 0; i < 20; i++ {
+		got, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got != healthy {
+			t.Fatalf("Get 选中了冷却中的代理 %s", got)
+		}
+	}
+
+	p.ReportFailure(healthy)
+	if _, err := p.Get(); err == nil {
+		t.Fatal("所有代理均在冷却中时 Get 应返回错误")
+	}
+
+	// 请求成功后结束冷却
+	p.ReportSuccess(healthy, 100*time.Millisecond)
+	if got, err := p.Get(); err != nil || got != healthy {
+		t.Fatalf("Get = %q, %v, want %q", got, err, healthy)
+	}
+}
+
+func TestGetPrefersPreferred(t *testing.T) {
+	p := NewPool(WithSources(&StaticSource{Proxies: addresses(0, 2)}))
+	if err := p.Update(context.Background()); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	proxies := p.Proxies()
+	p.SetPreferred(proxies[0], 200)
+
+	const rounds = 2000
+	picked := 0
+	for i := 0; i < rounds; i++ {
+		// 清除最近使用时间，排除轮换降权的影响
+		p.mu.Lock()
+		for _, entry := range p.proxies {
+			entry.lastUsed = time.Time{}
+		}
+		p.mu.Unlock()
+
+		got, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if got == proxies[0] {
+			picked++
+		}
+	}
+	if picked < rounds*6/10 {
+		t.Errorf("优选代理被选中 %d/%d 次，应明显多于普通代理", picked, rounds)
+	}
+}
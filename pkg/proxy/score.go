@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	latencyAlpha    = 0.3              // 响应时间指数移动平均的权重
+	baseCooldown    = 30 * time.Second // 第一次失败后的冷却时间，连续失败时翻倍
+	maxCooldown     = 30 * time.Minute // 冷却时间上限
+	demoteFailures  = 3                // 连续失败该次数后移出优选代理
+	rotationWindow  = 10 * time.Second // 该时间内刚被选中过的代理降低权重，使请求分散到多个代理
+	rotationPenalty = 0.25
+)
+
+// proxyEntry 代理池中单个代理的来源记录和评分数据
+type proxyEntry struct {
+	firstSeen time.Time // 首次出现在代理来源中的时间
+	lastSeen  time.Time // 最近一次出现在代理来源中的时间
+
+	preferred           bool
+	successes           int
+	failures            int
+	consecutiveFailures int
+	latency             float64   // 响应时间的指数移动平均（毫秒），0 表示尚无数据
+	cooldownUntil       time.Time // 冷却结束前不会被选中
+	lastUsed            time.Time
+}
+
+// recordSuccess 记录一次成功请求
+func (e *proxyEntry) recordSuccess(latency float64) {
+	e.successes++
+	e.consecutiveFailures = 0
+	e.cooldownUntil = time.Time{}
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = latencyAlpha*latency + (1-latencyAlpha)*e.latency
+	}
+}
+
+// recordFailure 记录一次失败请求，进入冷却期，连续失败时冷却时间按指数增长
+func (e *proxyEntry) recordFailure(now time.Time) {
+	e.failures++
+	e.consecutiveFailures++
+	if e.consecutiveFailures >= demoteFailures {
+		e.preferred = false
+	}
+
+	cooldown := baseCooldown * time.Duration(math.Pow(2, float64(e.consecutiveFailures-1)))
+	if cooldown > maxCooldown || cooldown <= 0 {
+		cooldown = maxCooldown
+	}
+	e.cooldownUntil = now.Add(cooldown)
+}
+
+func (e *proxyEntry) coolingDown(now time.Time) bool {
+	return now.Before(e.cooldownUntil)
+}
+
+// score 代理评分：平滑后的成功率 × 响应时间系数，优选代理加倍
+func (e *proxyEntry) score() float64 {
+	successRate := float64(e.successes+1) / float64(e.successes+e.failures+2)
+
+	latencyFactor := 0.5 // 尚无响应时间数据时按 1 秒计算
+	if e.latency > 0 {
+		latencyFactor = 1000 / (1000 + e.latency)
+	}
+
+	score := successRate * latencyFactor
+	if e.preferred {
+		score *= 2
+	}
+	return score
+}
+
+// weight 选择代理时使用的权重，刚被选中过的代理降低权重
+func (e *proxyEntry) weight(now time.Time) float64 {
+	weight := e.score()
+	if now.Sub(e.lastUsed) < rotationWindow {
+		weight *= rotationPenalty
+	}
+	return weight
+}
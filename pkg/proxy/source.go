@@ -0,0 +1,303 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	mylog "github.com/langchou/informer/pkg/log"
+)
+
+// ProxySource 代理来源，返回规范化后的代理地址列表
+type ProxySource interface {
+	Name() string
+	Fetch(ctx context.Context) ([]string, error)
+}
+
+// changeNotifier 可感知内容变化的代理来源，变化后代理池管理器会立即刷新
+type changeNotifier interface {
+	Changed() bool
+}
+
+// normalizeList 规范化代理地址，缺少协议的地址使用 defaultScheme，无效地址记录警告后跳过
+func normalizeList(source string, addresses []string, defaultScheme string) []string {
+	var proxies []string
+	for _, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address == "" || strings.HasPrefix(address, "#") {
+			continue
+		}
+		if defaultScheme != "" && !strings.Contains(address, "://") {
+			address = defaultScheme + "://" + address
+		}
+		proxy, err := Normalize(address)
+		if err != nil {
+			mylog.Warn(fmt.Sprintf("代理来源 %s 中的地址无效，已忽略: %v", source, err))
+			continue
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies
+}
+
+// StaticSource 配置文件中直接写明的代理列表
+type StaticSource struct {
+	Proxies []string
+}
+
+func (s *StaticSource) Name() string {
+	return "static"
+}
+
+func (s *StaticSource) Fetch(ctx context.Context) ([]string, error) {
+	return normalizeList(s.Name(), s.Proxies, ""), nil
+}
+
+// FileSource 本地文件中的代理列表，每行一个地址，# 开头的行为注释。
+// 文件修改时间变化后 Changed 返回 true
+type FileSource struct {
+	Path string
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+func (s *FileSource) Name() string {
+	return "file:" + s.Path
+}
+
+func (s *FileSource) Fetch(ctx context.Context) ([]string, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("读取代理文件失败: %v", err)
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("读取代理文件失败: %v", err)
+	}
+
+	s.mu.Lock()
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+
+	return normalizeList(s.Name(), strings.Split(string(data), "\n"), ""), nil
+}
+
+func (s *FileSource) Changed() bool {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !info.ModTime().Equal(s.modTime)
+}
+
+// JSONFields 描述 JSON 响应中代理列表的位置和字段名，路径使用 "." 分隔，例如 "data.list"
+type JSONFields struct {
+	List     string // 代理数组所在路径，为空表示响应本身就是数组
+	Address  string // 完整地址字段（host:port 或带协议的 URL），设置后忽略 IP/Port
+	IP       string
+	Port     string
+	Protocol string
+	Username string
+	Password string
+}
+
+// APISource 从 HTTP 接口获取代理，支持按行分隔的文本和 JSON 两种格式
+type APISource struct {
+	URL    string
+	Format string // text 或 json，默认 text
+	Fields JSONFields
+	// Protocol 地址未写明协议时使用的协议，默认 socks5
+	Protocol string
+	// Client 请求接口使用的客户端，为空时使用超时 10 秒的默认客户端
+	Client *http.Client
+}
+
+func (s *APISource) Name() string {
+	return "api:" + redactQuery(s.URL)
+}
+
+func (s *APISource) Fetch(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("无效的响应状态码: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+
+	if s.Format == "json" {
+		addresses, err := parseJSONProxies(body, s.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return normalizeList(s.Name(), addresses, s.Protocol), nil
+	}
+	return normalizeList(s.Name(), strings.Split(strings.TrimSpace(string(body)), "\n"), s.Protocol), nil
+}
+
+// parseJSONProxies 按 fields 从 JSON 响应中取出代理地址
+func parseJSONProxies(body []byte, fields JSONFields) ([]string, error) {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("解析 JSON 响应失败: %v", err)
+	}
+
+	list, ok := lookupPath(data, fields.List).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("JSON 响应中 %q 不是数组", fields.List)
+	}
+
+	var addresses []string
+	for _, item := range list {
+		// 数组元素直接是地址字符串
+		if address, ok := item.(string); ok {
+			addresses = append(addresses, address)
+			continue
+		}
+
+		var address string
+		if fields.Address != "" {
+			address = jsonString(lookupPath(item, fields.Address))
+		} else {
+			ip := jsonString(lookupPath(item, fields.IP))
+			port := jsonString(lookupPath(item, fields.Port))
+			if ip == "" || port == "" {
+				continue
+			}
+			address = net.JoinHostPort(ip, port)
+		}
+		if address == "" {
+			continue
+		}
+
+		if fields.Protocol != "" && !strings.Contains(address, "://") {
+			if protocol := strings.ToLower(jsonString(lookupPath(item, fields.Protocol))); protocol != "" {
+				address = protocol + "://" + address
+			}
+		}
+		if fields.Username != "" && !strings.Contains(address, "@") {
+			if username := jsonString(lookupPath(item, fields.Username)); username != "" {
+				userinfo := username
+				if password := jsonString(lookupPath(item, fields.Password)); password != "" {
+					userinfo += ":" + password
+				}
+				address = insertUserinfo(address, userinfo)
+			}
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// lookupPath 按 "." 分隔的路径取值，path 为空时返回 data 本身
+func lookupPath(data interface{}, path string) interface{} {
+	if path == "" {
+		return data
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		data = object[key]
+	}
+	return data
+}
+
+func jsonString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
+
+func insertUserinfo(address, userinfo string) string {
+	if i := strings.Index(address, "://"); i >= 0 {
+		return address[:i+3] + userinfo + "@" + address[i+3:]
+	}
+	return userinfo + "@" + address
+}
+
+// redactQuery 去掉 URL 中的查询参数，代理 API 通常在查询参数中携带密钥
+func redactQuery(rawURL string) string {
+	if i := strings.Index(rawURL, "?"); i >= 0 {
+		return rawURL[:i]
+	}
+	return rawURL
+}
+
+// MultiSource 合并多个代理来源并去重。部分来源失败时使用其余来源的结果，全部失败时返回错误
+type MultiSource []ProxySource
+
+func (m MultiSource) Name() string {
+	names := make([]string, len(m))
+	for i, source := range m {
+		names[i] = source.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (m MultiSource) Fetch(ctx context.Context) ([]string, error) {
+	seen := make(map[string]bool)
+	var proxies []string
+	var errs []string
+
+	for _, source := range m {
+		list, err := source.Fetch(ctx)
+		if err != nil {
+			mylog.Warn(fmt.Sprintf("代理来源 %s 获取失败: %v", source.Name(), err))
+			errs = append(errs, fmt.Sprintf("%s: %v", source.Name(), err))
+			continue
+		}
+		for _, proxy := range list {
+			if !seen[proxy] {
+				seen[proxy] = true
+				proxies = append(proxies, proxy)
+			}
+		}
+	}
+
+	if len(errs) == len(m) && len(m) > 0 {
+		return nil, fmt.Errorf("所有代理来源均获取失败: %s", strings.Join(errs, "; "))
+	}
+	return proxies, nil
+}
+
+func (m MultiSource) Changed() bool {
+	for _, source := range m {
+		if notifier, ok := source.(changeNotifier); ok && notifier.Changed() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultScheme 未写明协议的代理地址按 SOCKS5 处理
+const DefaultScheme = "socks5"
+
+// SupportedSchemes 支持的代理协议。socks5 在本地解析目标域名，socks5h 交给代理服务器解析
+var SupportedSchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// ParseURL 解析代理地址，未写明协议时按 DefaultScheme 处理
+func ParseURL(address string) (*url.URL, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return nil, fmt.Errorf("代理地址为空")
+	}
+	if !strings.Contains(address, "://") {
+		address = DefaultScheme + "://" + address
+	}
+
+	u, err := url.Parse(address)
+	if err != nil {
+		// url.Parse 的错误信息包含原始地址，可能带有密码
+		return nil, fmt.Errorf("代理地址格式错误: %s", Redact(address))
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !isSupportedScheme(u.Scheme) {
+		return nil, fmt.Errorf("不支持的代理协议: %s", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("代理地址缺少主机或端口: %s", Redact(address))
+	}
+	return u, nil
+}
+
+// Normalize 将代理地址规范为 scheme://[user:pass@]host:port 形式，作为代理池中的唯一标识
+func Normalize(address string) (string, error) {
+	u, err := ParseURL(address)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}).String(), nil
+}
+
+// Redact 隐藏代理地址中的密码，用于日志和管理接口输出
+func Redact(address string) string {
+	u, err := url.Parse(strings.TrimSpace(address))
+	if err == nil && u.Host != "" {
+		return u.Redacted()
+	}
+
+	// 无法解析时去掉 @ 之前的认证信息
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		prefix := ""
+		if j := strings.Index(address, "://"); j >= 0 && j < i {
+			prefix = address[:j+3]
+		}
+		return prefix + "xxxxx@" + address[i+1:]
+	}
+	return address
+}
+
+func isSupportedScheme(scheme string) bool {
+	for _, s := range SupportedSchemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
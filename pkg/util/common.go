@@ -3,9 +3,37 @@ package util
 import (
 	"crypto/sha256"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 func HashString(s string) string {
 	hash := sha256.Sum256([]byte(s))
 	return fmt.Sprintf("%x", hash)
 }
+
+var priceNumberRe = regexp.MustCompile(`\d+(?:,\d{3})*(?:\.\d+)?`)
+
+// ParsePrice 从价格文本中解析出数值，支持千分位逗号以及"万"/"w"/"k"单位，例如 "￥1,299元"、"1.2万"
+func ParsePrice(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	loc := priceNumberRe.FindStringIndex(s)
+	if loc == nil {
+		return 0, false
+	}
+
+	price, err := strconv.ParseFloat(strings.ReplaceAll(s[loc[0]:loc[1]], ",", ""), 64)
+	if err != nil || price <= 0 {
+		return 0, false
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(s[loc[1]:]))
+	switch {
+	case strings.HasPrefix(unit, "万"), strings.HasPrefix(unit, "w"):
+		price *= 10000
+	case strings.HasPrefix(unit, "k"):
+		price *= 1000
+	}
+	return price, true
+}